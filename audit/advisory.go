@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Advisory is a security advisory affecting a range of versions of a Composer package
+type Advisory struct {
+	ID               string `json:"id"`
+	Package          string `json:"package"`
+	Title            string `json:"title"`
+	CVE              string `json:"cve,omitempty"`
+	Link             string `json:"link,omitempty"`
+	Severity         string `json:"severity"`
+	AffectedVersions string `json:"affected_versions"`
+}
+
+// Database holds advisories by lowercase package name
+type Database map[string][]Advisory
+
+// packagistAdvisories is the format of the Packagist security advisories API,
+// https://packagist.org/apidoc#list-security-advisories
+type packagistAdvisories struct {
+	Advisories map[string][]struct {
+		AdvisoryID       string  `json:"advisoryId"`
+		PackageName      string  `json:"packageName"`
+		RemoteID         string  `json:"remoteId"`
+		Title            string  `json:"title"`
+		Link             string  `json:"link"`
+		CVE              *string `json:"cve"`
+		AffectedVersions string  `json:"affectedVersions"`
+		Severity         *string `json:"severity"`
+	} `json:"advisories"`
+}
+
+// friendsOfPHPAdvisory is the format of an advisory in the FriendsOfPHP security-advisories repository,
+// https://github.com/FriendsOfPHP/security-advisories
+type friendsOfPHPAdvisory struct {
+	Title     string                        `yaml:"title"`
+	Link      string                        `yaml:"link"`
+	CVE       string                        `yaml:"cve"`
+	Reference string                        `yaml:"reference"`
+	Branches  map[string]friendsOfPHPBranch `yaml:"branches"`
+}
+
+type friendsOfPHPBranch struct {
+	Versions []string `yaml:"versions"`
+}
+
+// Load reads advisories from a file or a directory tree. In a tree, such as a checkout of the FriendsOfPHP database,
+// YAML files are read in the FriendsOfPHP format and JSON files in the Packagist API format, while any other file and
+// JSON files without advisories, such as the composer.json of the checkout, are skipped. A single file without an
+// extension, such as a binding entry, is read in the format its content suggests.
+func Load(path string) (Database, error) {
+	db := Database{}
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if file != path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return db.loadFile(file, file == path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Len returns the number of advisories
+func (d Database) Len() int {
+	count := 0
+	for _, advisories := range d {
+		count += len(advisories)
+	}
+	return count
+}
+
+// Merge adds the advisories of another database
+func (d Database) Merge(other Database) {
+	for name, advisories := range other {
+		d[name] = append(d[name], advisories...)
+	}
+}
+
+func (d Database) loadFile(path string, single bool) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return d.loadPackagist(path, single)
+	case ".yaml", ".yml":
+		return d.loadFriendsOfPHP(path)
+	case "":
+		if !single {
+			return nil
+		}
+
+		// bindings have no extensions, so sniff the content
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if trimmed := strings.TrimSpace(string(buf)); strings.HasPrefix(trimmed, "{") {
+			return d.loadPackagist(path, true)
+		}
+		return d.loadFriendsOfPHP(path)
+	default:
+		return nil
+	}
+}
+
+// loadPackagist reads a file in the Packagist API format. A file without advisories is an error when it was given
+// on its own, and skipped when it was found in a tree.
+func (d Database) loadPackagist(path string, single bool) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	advisories := packagistAdvisories{}
+	if err := json.Unmarshal(buf, &advisories); err != nil {
+		if !single {
+			return nil
+		}
+		return fmt.Errorf("unable to parse advisories %s: %w", path, err)
+	}
+
+	if advisories.Advisories == nil {
+		if !single {
+			return nil
+		}
+		return fmt.Errorf("unable to parse advisories %s: no advisories found", path)
+	}
+
+	for name, list := range advisories.Advisories {
+		for _, a := range list {
+			advisory := Advisory{
+				ID:               a.AdvisoryID,
+				Package:          a.PackageName,
+				Title:            a.Title,
+				Link:             a.Link,
+				Severity:         SeverityUnknown,
+				AffectedVersions: a.AffectedVersions,
+			}
+			if advisory.Package == "" {
+				advisory.Package = name
+			}
+			if advisory.ID == "" {
+				advisory.ID = a.RemoteID
+			}
+			if a.CVE != nil {
+				advisory.CVE = *a.CVE
+			}
+			if a.Severity != nil {
+				advisory.Severity = normalizeSeverity(*a.Severity)
+			}
+
+			d.add(advisory)
+		}
+	}
+
+	return nil
+}
+
+func (d Database) loadFriendsOfPHP(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	a := friendsOfPHPAdvisory{}
+	if err := yaml.Unmarshal(buf, &a); err != nil {
+		return fmt.Errorf("unable to parse advisory %s: %w", path, err)
+	}
+
+	if !strings.HasPrefix(a.Reference, "composer://") {
+		return fmt.Errorf("unable to parse advisory %s: reference %q is not a composer:// reference", path, a.Reference)
+	}
+
+	// the versions of a branch must all match, any branch may match
+	var branches []string
+	for _, name := range sortedBranches(a.Branches) {
+		branches = append(branches, strings.Join(a.Branches[name].Versions, ","))
+	}
+
+	id := a.CVE
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	d.add(Advisory{
+		ID:               id,
+		Package:          strings.TrimPrefix(a.Reference, "composer://"),
+		Title:            a.Title,
+		CVE:              a.CVE,
+		Link:             a.Link,
+		Severity:         SeverityUnknown,
+		AffectedVersions: strings.Join(branches, "|"),
+	})
+
+	return nil
+}
+
+func (d Database) add(advisory Advisory) {
+	name := strings.ToLower(advisory.Package)
+	d[name] = append(d[name], advisory)
+}
+
+func sortedBranches(branches map[string]friendsOfPHPBranch) []string {
+	var names []string
+	for name := range branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
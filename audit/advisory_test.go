@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitAdvisory(t *testing.T) {
+	spec.Run(t, "Advisory", testAdvisory, spec.Report(report.Terminal{}))
+}
+
+func testAdvisory(t *testing.T, when spec.G, it spec.S) {
+	var root string
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		root = test.ScratchDir(t, "advisories")
+	})
+
+	it("reads the Packagist API format", func() {
+		test.WriteFile(t, filepath.Join(root, "advisories.json"), `{"advisories": {"monolog/monolog": [{
+	"advisoryId": "PKSA-dmw8-jd8k-q3c6",
+	"packageName": "monolog/monolog",
+	"title": "Header injection",
+	"link": "https://github.com/Seldaek/monolog/pull/683",
+	"cve": null,
+	"affectedVersions": ">=1.8.0,<1.12.0",
+	"severity": "moderate"
+}]}}`)
+
+		db, err := Load(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db["monolog/monolog"]).To(Equal([]Advisory{{
+			ID:               "PKSA-dmw8-jd8k-q3c6",
+			Package:          "monolog/monolog",
+			Title:            "Header injection",
+			Link:             "https://github.com/Seldaek/monolog/pull/683",
+			Severity:         SeverityMedium,
+			AffectedVersions: ">=1.8.0,<1.12.0",
+		}}))
+	})
+
+	it("reads the FriendsOfPHP tree", func() {
+		test.WriteFile(t, filepath.Join(root, "symfony", "http-kernel", "CVE-2019-18887.yaml"), `title: Use constant time comparison in UriSigner
+link: https://symfony.com/cve-2019-18887
+cve: CVE-2019-18887
+branches:
+    4.3.x:
+        time: 2019-11-13 07:39:40
+        versions: ['>=4.3.0', '<4.3.8']
+    3.4.x:
+        time: 2019-11-13 07:39:40
+        versions: ['>=2.0.0', '<3.4.35']
+reference: composer://symfony/http-kernel
+`)
+		test.WriteFile(t, filepath.Join(root, ".git", "config"), "ignored")
+
+		db, err := Load(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Len()).To(Equal(1))
+		Expect(db["symfony/http-kernel"][0].ID).To(Equal("CVE-2019-18887"))
+		Expect(db["symfony/http-kernel"][0].Severity).To(Equal(SeverityUnknown))
+		Expect(db["symfony/http-kernel"][0].AffectedVersions).To(Equal(">=2.0.0,<3.4.35|>=4.3.0,<4.3.8"))
+	})
+
+	it("sniffs the format of single files without an extension", func() {
+		test.WriteFile(t, filepath.Join(root, "packagist"), `{"advisories": {"acme/a": [{"advisoryId": "A", "affectedVersions": "<1.0"}]}}`)
+		test.WriteFile(t, filepath.Join(root, "friendsofphp"), "title: B\nreference: composer://acme/b\nbranches: {master: {versions: ['<2.0']}}\n")
+
+		db, err := Load(filepath.Join(root, "packagist"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db).To(HaveKey("acme/a"))
+
+		db, err = Load(filepath.Join(root, "friendsofphp"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db).To(HaveKey("acme/b"))
+	})
+
+	it("skips the files of a checkout that are not advisories", func() {
+		test.WriteFile(t, filepath.Join(root, "acme", "b", "2020-01-01.yaml"), "title: B\nreference: composer://acme/b\nbranches: {master: {versions: ['<2.0']}}\n")
+		test.WriteFile(t, filepath.Join(root, "README.md"), "# PHP Security Advisories Database\n")
+		test.WriteFile(t, filepath.Join(root, "LICENSE"), "This work is licensed under a Creative Commons license\n")
+		test.WriteFile(t, filepath.Join(root, "validator.php"), "<?php\n")
+		test.WriteFile(t, filepath.Join(root, "composer.json"), `{"require": {"symfony/yaml": "^5.0"}}`)
+
+		db, err := Load(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.Len()).To(Equal(1))
+		Expect(db).To(HaveKey("acme/b"))
+	})
+
+	it("rejects single files without advisories", func() {
+		test.WriteFile(t, filepath.Join(root, "composer.json"), `{"require": {}}`)
+
+		_, err := Load(filepath.Join(root, "composer.json"))
+		Expect(err).To(MatchError(ContainSubstring("no advisories found")))
+	})
+
+	it("rejects advisories that do not reference a Composer package", func() {
+		test.WriteFile(t, filepath.Join(root, "bad.yaml"), "title: B\nreference: npm://left-pad\n")
+
+		_, err := Load(root)
+		Expect(err).To(MatchError(ContainSubstring(`reference "npm://left-pad" is not a composer:// reference`)))
+	})
+}
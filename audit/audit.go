@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// Finding is an installed package affected by an advisory
+type Finding struct {
+	Advisory
+	Version string `json:"version"`
+}
+
+// Report is the result of auditing the installed packages
+type Report struct {
+	Sources    []string  `json:"sources"`
+	Advisories int       `json:"advisories"`
+	Packages   int       `json:"packages"`
+	Findings   []Finding `json:"findings"`
+	Unchecked  []string  `json:"unchecked,omitempty"`
+}
+
+var fourPartVersion = regexp.MustCompile(`^(\d+\.\d+\.\d+)\.\d+`)
+
+// Audit checks the packages against the advisories. Packages with versions that cannot be compared, like branches,
+// are reported as unchecked when there are advisories for them.
+func Audit(db Database, packages []manifest.Package) (Report, error) {
+	report := Report{Advisories: db.Len(), Packages: len(packages), Findings: []Finding{}}
+
+	for _, pkg := range packages {
+		advisories := db[strings.ToLower(pkg.Name)]
+		if len(advisories) == 0 {
+			continue
+		}
+
+		version, err := semver.NewVersion(normalizeVersion(pkg.Version))
+		if err != nil {
+			report.Unchecked = append(report.Unchecked, fmt.Sprintf("%s@%s", pkg.Name, pkg.Version))
+			continue
+		}
+
+		for _, advisory := range advisories {
+			affected, err := Affects(advisory.AffectedVersions, version)
+			if err != nil {
+				return Report{}, fmt.Errorf("unable to check advisory %s for %s: %w", advisory.ID, pkg.Name, err)
+			}
+
+			if affected {
+				report.Findings = append(report.Findings, Finding{Advisory: advisory, Version: pkg.Version})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		if report.Findings[i].Package != report.Findings[j].Package {
+			return report.Findings[i].Package < report.Findings[j].Package
+		}
+		return report.Findings[i].ID < report.Findings[j].ID
+	})
+
+	return report, nil
+}
+
+// Affects reports whether a version matches a Composer constraint like ">=1.0,<1.2.3|>=2.0,<2.0.1"
+func Affects(constraint string, version *semver.Version) (bool, error) {
+	for _, alternative := range strings.Split(constraint, "|") {
+		if strings.TrimSpace(alternative) == "" {
+			continue
+		}
+
+		var parts []string
+		for _, part := range strings.Split(alternative, ",") {
+			parts = append(parts, normalizeConstraint(part))
+		}
+
+		c, err := semver.NewConstraint(strings.Join(parts, ","))
+		if err != nil {
+			return false, err
+		}
+
+		if c.Check(version) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// normalizeVersion strips the `v` prefix and the fourth part of Composer's normalized versions, e.g. v1.2.3.0
+func normalizeVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	return fourPartVersion.ReplaceAllString(version, "$1")
+}
+
+func normalizeConstraint(constraint string) string {
+	constraint = strings.TrimSpace(constraint)
+	operator := strings.TrimRight(constraint, "v0123456789.-+abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	return operator + normalizeVersion(strings.TrimSpace(strings.TrimPrefix(constraint, operator)))
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitAudit(t *testing.T) {
+	spec.Run(t, "Audit", testAudit, spec.Report(report.Terminal{}))
+}
+
+func testAudit(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("matches Composer constraints", func() {
+		affects := func(constraint, version string) bool {
+			affected, err := Affects(constraint, semver.MustParse(normalizeVersion(version)))
+			Expect(err).NotTo(HaveOccurred())
+			return affected
+		}
+
+		Expect(affects(">=1.0,<1.2.3|>=2.0,<2.0.1", "1.2.2")).To(BeTrue())
+		Expect(affects(">=1.0,<1.2.3|>=2.0,<2.0.1", "1.2.3")).To(BeFalse())
+		Expect(affects(">=1.0,<1.2.3|>=2.0,<2.0.1", "v2.0.0")).To(BeTrue())
+		Expect(affects(">=v4.3.0,<4.3.8.0", "4.3.7.0")).To(BeTrue())
+	})
+
+	it("reports the affected packages", func() {
+		db := Database{
+			"monolog/monolog": {{ID: "A", Package: "monolog/monolog", AffectedVersions: "<1.12.0", Severity: SeverityHigh}},
+			"psr/log":         {{ID: "B", Package: "psr/log", AffectedVersions: "<1.0.0"}},
+			"acme/branch":     {{ID: "C", Package: "acme/branch", AffectedVersions: "<1.0.0"}},
+		}
+
+		report, err := Audit(db, []manifest.Package{
+			{Name: "Monolog/Monolog", Version: "1.11.0"},
+			{Name: "psr/log", Version: "1.1.2"},
+			{Name: "acme/branch", Version: "dev-main"},
+			{Name: "acme/safe", Version: "1.0.0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Packages).To(Equal(4))
+		Expect(report.Findings).To(Equal([]Finding{{Advisory: db["monolog/monolog"][0], Version: "1.11.0"}}))
+		Expect(report.Unchecked).To(ConsistOf("acme/branch@dev-main"))
+	})
+
+	it("returns an error for invalid constraints", func() {
+		_, err := Audit(Database{"acme/a": {{ID: "A", AffectedVersions: "~>nonsense"}}}, []manifest.Package{{Name: "acme/a", Version: "1.0.0"}})
+		Expect(err).To(MatchError(ContainSubstring("unable to check advisory A for acme/a")))
+	})
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"strings"
+)
+
+// Severities in increasing order. Advisories without a severity are ranked as high, so that datasets which do not
+// grade their advisories, like the FriendsOfPHP database, are not silently ignored.
+const (
+	SeverityNone     = "none"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+	SeverityUnknown  = "unknown"
+)
+
+var severityRanks = map[string]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityUnknown:  3,
+	SeverityCritical: 4,
+}
+
+// normalizeSeverity maps the severity of an advisory to one of the known severities
+func normalizeSeverity(severity string) string {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if severity == "moderate" {
+		return SeverityMedium
+	}
+	if _, ok := severityRanks[severity]; ok {
+		return severity
+	}
+	return SeverityUnknown
+}
+
+// Reaches reports whether a severity is at or above a threshold
+func Reaches(severity, threshold string) bool {
+	if threshold == SeverityNone {
+		return false
+	}
+	return severityRanks[normalizeSeverity(severity)] >= severityRanks[threshold]
+}
@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitSeverity(t *testing.T) {
+	spec.Run(t, "Severity", testSeverity, spec.Report(report.Terminal{}))
+}
+
+func testSeverity(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("compares severities with thresholds", func() {
+		Expect(Reaches(SeverityCritical, SeverityHigh)).To(BeTrue())
+		Expect(Reaches(SeverityMedium, SeverityHigh)).To(BeFalse())
+		Expect(Reaches("moderate", SeverityMedium)).To(BeTrue())
+		Expect(Reaches("", SeverityHigh)).To(BeTrue())
+		Expect(Reaches(SeverityCritical, SeverityNone)).To(BeFalse())
+	})
+}
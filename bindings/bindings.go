@@ -0,0 +1,192 @@
+package bindings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+)
+
+// DefaultRoot is where the platform provides bindings when neither SERVICE_BINDING_ROOT nor CNB_BINDINGS is set
+const DefaultRoot = "/platform/bindings"
+
+// Binding is a service binding provided by the platform. Each entry of a binding is a file in its directory.
+type Binding struct {
+	Name     string
+	Path     string
+	Type     string
+	Provider string
+	Entries  map[string]string
+}
+
+// Resolve reads the bindings from SERVICE_BINDING_ROOT, falling back to CNB_BINDINGS and the default platform location
+func Resolve() ([]Binding, error) {
+	root := os.Getenv("SERVICE_BINDING_ROOT")
+	if root == "" {
+		root = os.Getenv("CNB_BINDINGS")
+	}
+	if root == "" {
+		root = DefaultRoot
+	}
+
+	return ResolveFrom(root)
+}
+
+// ResolveFrom reads the bindings in a directory. Both the Service Binding spec layout (a `type` file next to the
+// entries) and the older CNB layout (`metadata/kind` and a `secret` directory) are supported.
+func ResolveFrom(root string) ([]Binding, error) {
+	if exists, err := helper.FileExists(root); err != nil || !exists {
+		return nil, err
+	}
+
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []Binding
+	for _, dir := range dirs {
+		if strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(root, dir.Name())
+
+		// bindings may be symlinked directories
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			continue
+		}
+
+		binding, err := readBinding(path)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// OfType returns the bindings with one of the given types. Types are compared case-insensitively.
+func OfType(bindings []Binding, types ...string) []Binding {
+	var matches []Binding
+	for _, binding := range bindings {
+		for _, t := range types {
+			if strings.EqualFold(binding.Type, t) {
+				matches = append(matches, binding)
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// Value returns the trimmed content of an entry
+func (b Binding) Value(key string) (string, bool, error) {
+	path, ok := b.Entries[key]
+	if !ok {
+		return "", false, nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	return strings.TrimSpace(string(buf)), true, nil
+}
+
+// Keys returns the names of the entries of the binding, sorted
+func (b Binding) Keys() []string {
+	var keys []string
+	for key := range b.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func readBinding(path string) (Binding, error) {
+	binding := Binding{Name: filepath.Base(path), Path: path}
+
+	secretDir := path
+	if legacy, err := helper.FileExists(filepath.Join(path, "metadata", "kind")); err != nil {
+		return Binding{}, err
+	} else if legacy {
+		secretDir = filepath.Join(path, "secret")
+		if binding.Type, err = readTrimmed(filepath.Join(path, "metadata", "kind")); err != nil {
+			return Binding{}, err
+		}
+		if binding.Provider, err = readOptional(filepath.Join(path, "metadata", "provider")); err != nil {
+			return Binding{}, err
+		}
+	}
+
+	entries, err := readEntries(secretDir)
+	if err != nil {
+		return Binding{}, err
+	}
+
+	if binding.Type == "" {
+		if binding.Type, err = readOptional(entries["type"]); err != nil {
+			return Binding{}, err
+		}
+		if binding.Provider, err = readOptional(entries["provider"]); err != nil {
+			return Binding{}, err
+		}
+		delete(entries, "type")
+		delete(entries, "provider")
+	}
+	binding.Entries = entries
+
+	return binding, nil
+}
+
+func readEntries(dir string) (map[string]string, error) {
+	entries := map[string]string{}
+	if exists, err := helper.FileExists(dir); err != nil || !exists {
+		return entries, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		// Kubernetes projects secrets through hidden ..data directories and symlinks
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		entries[file.Name()] = filepath.Join(dir, file.Name())
+	}
+
+	return entries, nil
+}
+
+func readOptional(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	if exists, err := helper.FileExists(path); err != nil || !exists {
+		return "", err
+	}
+
+	return readTrimmed(path)
+}
+
+func readTrimmed(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(buf)), nil
+}
@@ -0,0 +1,95 @@
+package bindings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitBindings(t *testing.T) {
+	spec.Run(t, "Bindings", testBindings, spec.Report(report.Terminal{}))
+}
+
+func testBindings(t *testing.T, when spec.G, it spec.S) {
+	var root string
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		root = test.ScratchDir(t, "bindings")
+	})
+
+	when("bindings follow the Service Binding spec", func() {
+		it("reads the type, provider and entries", func() {
+			test.WriteFile(t, filepath.Join(root, "satis", "type"), "composer-auth\n")
+			test.WriteFile(t, filepath.Join(root, "satis", "provider"), "acme")
+			test.WriteFile(t, filepath.Join(root, "satis", "host"), "satis.example.com\n")
+			test.WriteFile(t, filepath.Join(root, "satis", "..data", "host"), "ignored")
+			test.WriteFile(t, filepath.Join(root, "not-a-binding"), "")
+
+			bindings, err := ResolveFrom(root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bindings).To(HaveLen(1))
+			Expect(bindings[0].Name).To(Equal("satis"))
+			Expect(bindings[0].Type).To(Equal("composer-auth"))
+			Expect(bindings[0].Provider).To(Equal("acme"))
+			Expect(bindings[0].Keys()).To(Equal([]string{"host"}))
+
+			host, ok, err := bindings[0].Value("host")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(host).To(Equal("satis.example.com"))
+
+			_, ok, err = bindings[0].Value("password")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	when("bindings follow the older CNB layout", func() {
+		it("reads the kind and the secrets", func() {
+			test.WriteFile(t, filepath.Join(root, "gitlab", "metadata", "kind"), "composer")
+			test.WriteFile(t, filepath.Join(root, "gitlab", "secret", "gitlab-token"), "token")
+
+			bindings, err := ResolveFrom(root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bindings).To(HaveLen(1))
+			Expect(bindings[0].Type).To(Equal("composer"))
+			Expect(bindings[0].Entries).To(HaveKeyWithValue("gitlab-token", filepath.Join(root, "gitlab", "secret", "gitlab-token")))
+		})
+	})
+
+	when("resolving bindings from the environment", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("SERVICE_BINDING_ROOT")).To(Succeed())
+		})
+
+		it("uses SERVICE_BINDING_ROOT", func() {
+			test.WriteFile(t, filepath.Join(root, "one", "type"), "ca-certificates")
+			Expect(os.Setenv("SERVICE_BINDING_ROOT", root)).To(Succeed())
+
+			bindings, err := Resolve()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bindings).To(HaveLen(1))
+		})
+
+		it("returns no bindings when the root does not exist", func() {
+			Expect(os.Setenv("SERVICE_BINDING_ROOT", filepath.Join(root, "missing"))).To(Succeed())
+
+			bindings, err := Resolve()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bindings).To(BeEmpty())
+		})
+	})
+
+	when("filtering bindings", func() {
+		it("matches types case-insensitively", func() {
+			bindings := []Binding{{Name: "a", Type: "Composer"}, {Name: "b", Type: "composer-auth"}, {Name: "c", Type: "mysql"}}
+			Expect(OfType(bindings, "composer", "composer-auth")).To(Equal(bindings[:2]))
+		})
+	})
+}
@@ -50,6 +50,10 @@ func runBuild(context build.Build) (int, error) {
 		}
 
 		err = packageContributor.Contribute()
+		if failure, ok := composer.ClassifyFailure(err); ok {
+			failure.Log(context.Logger)
+			return context.Failure(failure.Code), err
+		}
 		if err != nil {
 			return context.Failure(106), err
 		}
@@ -1,21 +1,21 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
+	"sort"
 
 	"github.com/cloudfoundry/libcfbuildpack/buildpackplan"
 
 	"github.com/buildpack/libbuildpack/buildplan"
 	"github.com/cloudfoundry/libcfbuildpack/detect"
-	"github.com/cloudfoundry/libcfbuildpack/helper"
 	"github.com/cloudfoundry/libcfbuildpack/logger"
 	"github.com/paketo-buildpacks/php-composer/composer"
 )
 
+// ExtensionsMetadata is the key of the PHP extensions the app requires in the metadata of the php build plan entry
+const ExtensionsMetadata = "extensions"
+
 func main() {
 	context, err := detect.DefaultDetect()
 	if err != nil {
@@ -32,34 +32,61 @@ func main() {
 }
 
 func runDetect(context detect.Detect) (int, error) {
-	buildpackYAML, err := composer.LoadComposerBuildpackYAML(context.Application.Root)
+	cfg, err := composer.LoadConfig(context.Application.Root)
 	if err != nil {
 		return context.Fail(), err
 	}
 
-	path, err := composer.FindComposer(context.Application.Root, buildpackYAML.Composer.JsonPath)
+	paths, err := composer.FindProjects(context.Application.Root, cfg)
 	if err != nil {
 		return context.Fail(), err
 	}
 
-	phpVersion, phpVersionSrc, err := findPHPVersion(path, context.Logger)
+	for _, path := range paths {
+		if err := composer.ValidateLock(path, cfg.LockValidation, context.Logger); err != nil {
+			return context.Fail(), err
+		}
+	}
+
+	// the first project decides the PHP version, while every project needs its extensions
+	phpVersion, phpVersionSrc, err := findPHPVersion(paths[0], context.Logger)
 	if err != nil {
 		return context.Fail(), err
 	}
 
+	var extensions []string
+	for _, path := range paths {
+		projectExtensions, err := findExtensions(path, !composer.Contains(cfg.InstallOptions, "--no-dev"))
+		if err != nil {
+			return context.Fail(), err
+		}
+
+		for _, extension := range projectExtensions {
+			if !composer.Contains(extensions, extension) {
+				extensions = append(extensions, extension)
+			}
+		}
+	}
+	sort.Strings(extensions)
+
+	phpMetadata := buildplan.Metadata{
+		"build":                     true,
+		buildpackplan.VersionSource: phpVersionSrc,
+	}
+	if len(extensions) > 0 {
+		phpMetadata[ExtensionsMetadata] = extensions
+	}
+
 	return context.Pass(buildplan.Plan{
 		Requires: []buildplan.Required{
 			{
-				Name:    "php",
-				Version: phpVersion,
-				Metadata: buildplan.Metadata{
-					"build":                     true,
-					buildpackplan.VersionSource: phpVersionSrc,
-				},
+				Name:     "php",
+				Version:  phpVersion,
+				Metadata: phpMetadata,
 			},
 			{
 				Name:    composer.Dependency,
-				Version: buildpackYAML.Composer.Version,
+				Version: cfg.Version,
 			},
 		},
 		Provides: []buildplan.Provided{{Name: composer.Dependency}},
@@ -67,66 +94,33 @@ func runDetect(context detect.Detect) (int, error) {
 }
 
 func findPHPVersion(path string, logger logger.Logger) (string, string, error) {
-	composerLockPath := filepath.Join(filepath.Dir(path), composer.ComposerLock)
-
-	composerLockExists, err := helper.FileExists(composerLockPath)
-	if err != nil {
-		return "", "", err
-	}
-
-	if composerLockExists {
-		return parseComposerLock(composerLockPath)
-	}
-
-	logger.Info("WARNING: Include a 'composer.lock' file with your application! This will make sure the exact same version of dependencies are used when you deploy to CloudFoundry. It will also enable caching of your dependency layer.")
-
-	return parseComposerJSON(path)
-}
-
-func parseComposerJSON(path string) (string, string, error) {
-	buf, err := ioutil.ReadFile(path)
+	requirements, err := composer.ReadPlatformRequirements(path, false)
 	if err != nil {
 		return "", "", err
 	}
 
-	type composerRequire struct {
-		Php string `json:"php"`
+	if requirements.Source != composer.ComposerLock {
+		logger.Info("WARNING: Include a 'composer.lock' file with your application! This will make sure the exact same version of dependencies are used when you deploy to CloudFoundry. It will also enable caching of your dependency layer.")
 	}
 
-	composerJSON := struct {
-		Require composerRequire `json:"require"`
-	}{}
-
-	if err := json.Unmarshal(buf, &composerJSON); err != nil {
-		return "", "", err
+	// an empty platform, which PHP writes as an array, doesn't tell us the PHP version
+	// return empty string to accept default PHP version & don't error
+	php, _ := requirements.Find("php")
+	phpVersion := php.Constraint(composer.RootPackage)
+	if phpVersion == "" {
+		return "", "", nil
 	}
 
-	return composerJSON.Require.Php, "composer.json", nil
+	return phpVersion, requirements.Source, nil
 }
 
-func parseComposerLock(path string) (string, string, error) {
-	buf, err := ioutil.ReadFile(path)
+// findExtensions reads the extensions required by composer.json or by any locked package, so that the PHP buildpack
+// can provide them before Composer runs
+func findExtensions(path string, dev bool) ([]string, error) {
+	requirements, err := composer.ReadPlatformRequirements(path, dev)
 	if err != nil {
-		return "", "", err
-	}
-
-	// Composer.lock -> platform can be a dict or an array
-	type composerLockPlatform struct {
-		Php string `json:"php"`
-	}
-
-	composerLock := struct {
-		Platform composerLockPlatform `json:"platform"`
-	}{}
-
-	if err := json.Unmarshal(buf, &composerLock); err != nil {
-		// this happens when it's an array, which doesn't tell us the PHP version
-		// return empty string to accept default PHP version & don't error
-		if err.Error() == "json: cannot unmarshal array into Go struct field .platform of type main.composerLockPlatform" {
-			return "", "", nil
-		}
-		return "", "", err
+		return nil, err
 	}
 
-	return composerLock.Platform.Php, "composer.lock", nil
+	return requirements.Extensions(), nil
 }
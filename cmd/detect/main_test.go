@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -74,6 +75,54 @@ func testDetect(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("composer.json and composer.lock require extensions", func() {
+		it.Before(func() {
+			test.WriteFile(t, filepath.Join(factory.Detect.Application.Root, composer.ComposerJSON), `{"require": {"php": ">=7.2", "ext-gd": "*"}}`)
+			test.WriteFile(t, filepath.Join(factory.Detect.Application.Root, composer.ComposerLock), `{
+	"packages": [{"name": "doctrine/dbal", "version": "2.10.0", "require": {"ext-pdo": "*", "php": "^7.2"}}],
+	"packages-dev": [{"name": "phpunit/phpunit", "version": "9.0.0", "require": {"ext-dom": "*"}}],
+	"platform": {"php": ">=7.2", "ext-gd": "*"}
+}`)
+		})
+
+		it("adds the extensions to the php build plan metadata", func() {
+			code, err := runDetect(factory.Detect)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(detect.PassStatusCode))
+
+			Expect(factory.Plans.Plan.Requires[0]).To(Equal(buildplan.Required{
+				Name:    "php",
+				Version: ">=7.2",
+				Metadata: buildplan.Metadata{
+					"build":                     true,
+					buildpackplan.VersionSource: "composer.lock",
+					ExtensionsMetadata:          []string{"gd", "pdo"},
+				},
+			}))
+		})
+	})
+
+	when("there are several projects", func() {
+		it.Before(func() {
+			test.WriteFile(t, filepath.Join(factory.Detect.Application.Root, composer.ComposerJSON), `{"require": {"php": ">=7.2", "ext-gd": "*"}}`)
+			test.WriteFile(t, filepath.Join(factory.Detect.Application.Root, "worker", composer.ComposerJSON), `{"require": {"php": ">=7.4", "ext-amqp": "*"}}`)
+			Expect(os.Setenv(composer.ProjectsEnv, ".,worker")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv(composer.ProjectsEnv)).To(Succeed())
+		})
+
+		it("takes the PHP version from the first project and the extensions of every project", func() {
+			code, err := runDetect(factory.Detect)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(detect.PassStatusCode))
+
+			Expect(factory.Plans.Plan.Requires[0].Version).To(Equal(">=7.2"))
+			Expect(factory.Plans.Plan.Requires[0].Metadata[ExtensionsMetadata]).To(Equal([]string{"amqp", "gd"}))
+		})
+	})
+
 	when("there is a composer.json but not a composer.lock", func() {
 		var (
 			compsoserPath string
@@ -188,6 +237,25 @@ func testDetect(t *testing.T, when spec.G, it spec.S) {
 				}))
 			})
 		})
+
+		when("BP_COMPOSER_VERSION is set", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_COMPOSER_VERSION")).To(Succeed())
+			})
+
+			it("should prefer the environment variable over buildpack.yml", func() {
+				test.WriteFile(t, filepath.Join(factory.Detect.Application.Root, "buildpack.yml"), `{"composer": {"version": "1.2.3"}}`)
+				Expect(os.Setenv("BP_COMPOSER_VERSION", "2.*")).To(Succeed())
+
+				code, err := runDetect(factory.Detect)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(code).To(Equal(detect.PassStatusCode))
+				Expect(factory.Plans.Plan.Requires).To(ContainElement(buildplan.Required{
+					Name:    composer.Dependency,
+					Version: "2.*",
+				}))
+			})
+		})
 	})
 
 	when("there is no composer.json", func() {
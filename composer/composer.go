@@ -1,12 +1,14 @@
 package composer
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/cloudfoundry/libcfbuildpack/helper"
@@ -20,6 +22,7 @@ const (
 	Dependency         = "composer"
 	PackagesDependency = "php-composer-packages"
 	CacheDependency    = "php-composer-cache"
+	AuditDependency    = "php-composer-audit"
 	ComposerLock       = "composer.lock"
 	ComposerJSON       = "composer.json"
 	ComposerPHAR       = "composer.phar"
@@ -28,8 +31,18 @@ const (
 
 // Composer runner
 type Composer struct {
-	Logger     logger.Logger
-	Runner     runner.Runner
+	Logger logger.Logger
+	Runner runner.Runner
+
+	// Deadline bounds every command when it is set, see BP_COMPOSER_TIMEOUT
+	Deadline time.Time
+
+	// CommandTimeout bounds each command when it is not zero, see BP_COMPOSER_COMMAND_TIMEOUT
+	CommandTimeout time.Duration
+
+	// Output receives a copy of the output of every command it is set for
+	Output io.Writer
+
 	workingDir string
 	pharPath   string
 }
@@ -46,21 +59,43 @@ func NewComposer(composerJsonPath, composerPharPath string, logger logger.Logger
 	}
 }
 
+// NewComposerForConfig creates a Composer runner whose output follows BP_COMPOSER_OUTPUT
+func NewComposerForConfig(composerJsonPath, composerPharPath string, logger logger.Logger, cfg Config) Composer {
+	c := NewComposer(composerJsonPath, composerPharPath, logger)
+	c.CommandTimeout = cfg.CommandTimeout
+	if cfg.Output == OutputStructured {
+		c.Runner = runner.StructuredRunner{Logger: logger}
+	}
+	return c
+}
+
 // Install runs `composer install`
 func (c Composer) Install(args ...string) error {
 	args = append([]string{c.pharPath, "install", "--no-progress"}, args...)
-	return c.Runner.Run("php", c.workingDir, args...)
+	return c.run(args...)
+}
+
+// RunScript runs `composer run-script` for an event
+func (c Composer) RunScript(event string, args ...string) error {
+	args = append([]string{c.pharPath, "run-script", event}, args...)
+	return c.run(args...)
+}
+
+// DumpAutoload runs `composer dump-autoload`
+func (c Composer) DumpAutoload(args ...string) error {
+	args = append([]string{c.pharPath, "dump-autoload"}, args...)
+	return c.run(args...)
 }
 
 // Version runs `composer version`
 func (c Composer) Version() error {
-	return c.Runner.Run("php", c.workingDir, c.pharPath, "-V")
+	return c.run(c.pharPath, "-V")
 }
 
 // Global runs `composer global`
 func (c Composer) Global(args ...string) error {
 	args = append([]string{c.pharPath, "global", "require", "--no-progress"}, args...)
-	return c.Runner.Run("php", c.workingDir, args...)
+	return c.run(args...)
 }
 
 // Config runs `composer config`
@@ -70,33 +105,73 @@ func (c Composer) Config(key, value string, global bool) error {
 		args = append(args, "-g")
 	}
 	args = append(args, key, value)
-	return c.Runner.Run("php", c.workingDir, args...)
+	return c.run(args...)
 }
 
-// CheckPlatformReqs looks for required extension
-func (c Composer) CheckPlatformReqs() ([]string, error) {
+// ConfigList runs `composer config` for settings that take several values, such as github-domains or http-basic
+// credentials
+func (c Composer) ConfigList(key string, values []string, global bool) error {
+	args := []string{c.pharPath, "config"}
+	if global {
+		args = append(args, "-g")
+	}
+	args = append(append(args, key), values...)
+	return c.run(args...)
+}
 
-	// let Composer tell us what extensions are required
-	output, err := c.Runner.RunWithOutput("php", c.workingDir, c.pharPath, "check-platform-reqs")
-	if err != nil {
-		exitError, ok := err.(*exec.ExitError)
+// run runs Composer with PHP until it finishes, the deadline passes or the command timeout expires
+func (c Composer) run(args ...string) error {
+	ctx, cancel := c.context()
+	defer cancel()
 
-		if !ok || exitError.ExitCode() != 2 {
-			return []string{}, err
+	if c.Output != nil {
+		ctx = runner.WithOutput(ctx, c.Output)
+	}
+	return c.Runner.Run(ctx, "php", c.workingDir, args...)
+}
+
+// context returns the context of a single command, which ends at the deadline or at the command timeout, whichever
+// comes first
+func (c Composer) context() (context.Context, context.CancelFunc) {
+	deadline := c.Deadline
+	if c.CommandTimeout > 0 {
+		if commandDeadline := time.Now().Add(c.CommandTimeout); deadline.IsZero() || commandDeadline.Before(deadline) {
+			deadline = commandDeadline
 		}
 	}
 
-	extensions := []string{}
-	for _, line := range strings.Split(output, "\n") {
-		chunks := strings.Split(strings.TrimSpace(line), " ")
-		extensionName := strings.TrimPrefix(strings.TrimSpace(chunks[0]), "ext-")
-		extensionStatus := strings.TrimSpace(chunks[len(chunks)-1])
-		if extensionName != "php" && extensionStatus == "missing" {
-			extensions = append(extensions, extensionName)
+	if deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+// CheckPlatformReqs looks for required extension
+func (c Composer) CheckPlatformReqs() ([]string, error) {
+	requirements, err := c.PlatformRequirements()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return requirements.MissingExtensions(), nil
+}
+
+// PlatformRequirements runs `composer check-platform-reqs` and parses every requirement it reports
+func (c Composer) PlatformRequirements(args ...string) (PlatformRequirements, error) {
+	// let Composer tell us what extensions are required
+	args = append([]string{c.pharPath, "check-platform-reqs"}, args...)
+	ctx, cancel := c.context()
+	defer cancel()
+
+	output, err := c.Runner.RunWithOutput(ctx, "php", c.workingDir, args...)
+	if err != nil {
+		// exit code 2 means that requirements are not met, which is what we are asking about
+		if code, ok := runner.ExitCode(err); !ok || code != 2 {
+			return PlatformRequirements{}, err
 		}
 	}
 
-	return extensions, nil
+	return ParsePlatformReqs(output), nil
 }
 
 // FindComposer locates the composer JSON and composer lock files
@@ -130,6 +205,47 @@ func FindComposer(appRoot string, composerJSONPath string) (string, error) {
 	return "", fmt.Errorf(`no "%s" found in the following locations: %v`, ComposerJSON, paths)
 }
 
+// FindProjects locates the composer.json of every project listed in BP_COMPOSER_PROJECTS, in order. Projects are
+// directories relative to the app root. Without a list the app is a single project found by FindComposer.
+func FindProjects(appRoot string, cfg Config) ([]string, error) {
+	if len(cfg.Projects) == 0 {
+		path, err := FindComposer(appRoot, cfg.JsonPath)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, project := range cfg.Projects {
+		dir := filepath.Join(appRoot, project)
+		if filepath.Base(dir) == ComposerJSON {
+			dir = filepath.Dir(dir)
+		}
+
+		if rel, err := filepath.Rel(appRoot, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("project %q in %s is outside of the app root", project, ProjectsEnv)
+		}
+
+		path := filepath.Join(dir, ComposerJSON)
+		if seen[path] {
+			return nil, fmt.Errorf("project %q is listed more than once in %s", project, ProjectsEnv)
+		}
+		seen[path] = true
+
+		if exists, err := helper.FileExists(path); err != nil {
+			return nil, fmt.Errorf("error checking filepath: %s", path)
+		} else if !exists {
+			return nil, fmt.Errorf(`no "%s" found for project %q in %s`, ComposerJSON, project, ProjectsEnv)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
 type ComposerConfig struct {
 	Version         string   `yaml:"version"`
 	InstallOptions  []string `yaml:"install_options"`
@@ -144,10 +260,25 @@ type BuildpackYAML struct {
 
 // LoadComposerBuildpackYAML loads the buildpack YAML from disk
 func LoadComposerBuildpackYAML(appRoot string) (BuildpackYAML, error) {
-	buildpackYAML, configFile := BuildpackYAML{}, filepath.Join(appRoot, "buildpack.yml")
+	buildpackYAML, err := loadBuildpackYAML(appRoot)
+	if err != nil {
+		return BuildpackYAML{}, err
+	}
+
+	if buildpackYAML.Composer.InstallOptions == nil {
+		buildpackYAML.Composer.InstallOptions = []string{DefaultInstallOption}
+	}
+
+	if buildpackYAML.Composer.VendorDirectory == "" {
+		buildpackYAML.Composer.VendorDirectory = DefaultVendorDirectory
+	}
+
+	return buildpackYAML, nil
+}
 
-	buildpackYAML.Composer.InstallOptions = []string{"--no-dev"}
-	buildpackYAML.Composer.VendorDirectory = "vendor"
+// loadBuildpackYAML loads the buildpack YAML from disk without applying any defaults
+func loadBuildpackYAML(appRoot string) (BuildpackYAML, error) {
+	buildpackYAML, configFile := BuildpackYAML{}, filepath.Join(appRoot, "buildpack.yml")
 
 	if exists, err := helper.FileExists(configFile); err != nil {
 		return BuildpackYAML{}, err
@@ -172,47 +303,34 @@ func LoadComposerBuildpackYAML(appRoot string) (BuildpackYAML, error) {
 }
 
 func WarnComposerBuildpackYAML(logger logger.Logger, version, appRoot string) error {
-	var (
-		exists bool
-		err    error
-	)
-	buildpackYAML, configFile := BuildpackYAML{}, filepath.Join(appRoot, "buildpack.yml")
-	if exists, err = helper.FileExists(configFile); err != nil {
+	exists, err := helper.FileExists(filepath.Join(appRoot, "buildpack.yml"))
+	if err != nil {
 		return err
 	}
 	if !exists {
 		return nil
 	}
-	file, err := os.Open(configFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
 
-	contents, err := ioutil.ReadAll(file)
+	buildpackYAML, err := loadBuildpackYAML(appRoot)
 	if err != nil {
 		return err
 	}
 
-	err = yaml.Unmarshal(contents, &buildpackYAML)
-	if err != nil {
-		return err
-	}
 	fieldMapping := map[string]string{}
 	if buildpackYAML.Composer.Version != "" {
-		fieldMapping["composer.version"] = "BP_COMPOSER_VERSION"
+		fieldMapping["composer.version"] = VersionEnv
 	}
 	if len(buildpackYAML.Composer.InstallOptions) > 0 {
-		fieldMapping["composer.install_options"] = "BP_COMPOSER_INSTALL_OPTIONS"
+		fieldMapping["composer.install_options"] = InstallOptionsEnv
 	}
 	if buildpackYAML.Composer.VendorDirectory != "" {
-		fieldMapping["composer.vendor_directory"] = "COMPOSER_VENDOR_DIR"
+		fieldMapping["composer.vendor_directory"] = VendorDirectoryEnv
 	}
 	if buildpackYAML.Composer.JsonPath != "" {
-		fieldMapping["composer.json_path"] = "COMPOSER"
+		fieldMapping["composer.json_path"] = JsonPathEnv
 	}
 	if len(buildpackYAML.Composer.InstallGlobal) > 0 {
-		fieldMapping["composer.install_global"] = "BP_COMPOSER_GLOBAL_INSTALL_OPTIONS"
+		fieldMapping["composer.install_global"] = GlobalInstallOptionsEnv
 	}
 
 	nextMajorVersion := semver.MustParse(version).IncMajor()
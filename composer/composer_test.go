@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"path/filepath"
 	"testing"
+	"time"
 
 	bp "github.com/buildpack/libbuildpack/logger"
 	"github.com/cloudfoundry/libcfbuildpack/logger"
@@ -49,6 +50,16 @@ func testComposer(t *testing.T, when spec.G, it spec.S) {
 			Expect(fakeRunner.Arguments).To(ConsistOf("php", expectedPharPath, "install", "--no-progress", "--foo", "--bar"))
 		})
 
+		it("should run composer run-script", func() {
+			Expect(comp.RunScript("post-install-cmd", "--no-dev")).To(Succeed())
+			Expect(fakeRunner.Arguments).To(Equal([]string{"php", expectedPharPath, "run-script", "post-install-cmd", "--no-dev"}))
+		})
+
+		it("should run composer dump-autoload", func() {
+			Expect(comp.DumpAutoload("--no-scripts", "--optimize")).To(Succeed())
+			Expect(fakeRunner.Arguments).To(Equal([]string{"php", expectedPharPath, "dump-autoload", "--no-scripts", "--optimize"}))
+		})
+
 		it("should run composer global", func() {
 			Expect(comp.Global("--foo", "--bar")).To(Succeed())
 			Expect(fakeRunner.Arguments).To(ConsistOf("php", expectedPharPath, "global", "require", "--no-progress", "--foo", "--bar"))
@@ -61,6 +72,44 @@ func testComposer(t *testing.T, when spec.G, it spec.S) {
 			Expect(comp.Config("key", "val", false))
 			Expect(fakeRunner.Arguments).To(ConsistOf("php", expectedPharPath, "config", "key", `val`))
 		})
+
+		it("should run config with several values", func() {
+			Expect(comp.ConfigList("github-domains", []string{"github.com", "github.example.com"}, true)).To(Succeed())
+			Expect(fakeRunner.Arguments).To(Equal([]string{"php", expectedPharPath, "config", "-g", "github-domains", "github.com", "github.example.com"}))
+		})
+
+		it("bounds each command by the deadline and the command timeout", func() {
+			Expect(comp.Version()).To(Succeed())
+			_, ok := fakeRunner.Context.Deadline()
+			Expect(ok).To(BeFalse())
+
+			comp.Deadline = time.Now().Add(time.Hour)
+			comp.CommandTimeout = time.Minute
+			Expect(comp.Version()).To(Succeed())
+			deadline, ok := fakeRunner.Context.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+
+			comp.Deadline = time.Now().Add(time.Second)
+			Expect(comp.Version()).To(Succeed())
+			deadline, _ = fakeRunner.Context.Deadline()
+			Expect(deadline).To(Equal(comp.Deadline))
+		})
+	})
+
+	when("creating a runner for the configuration", func() {
+		it("renders structured output when asked to", func() {
+			comp := NewComposerForConfig("/app", "/composer", factory.Build.Logger, Config{Output: OutputStructured})
+			Expect(comp.Runner).To(BeAssignableToTypeOf(runner.StructuredRunner{}))
+
+			comp = NewComposerForConfig("/app", "/composer", factory.Build.Logger, Config{Output: OutputPlain})
+			Expect(comp.Runner).To(BeAssignableToTypeOf(runner.ComposerRunner{}))
+		})
+
+		it("applies the command timeout", func() {
+			comp := NewComposerForConfig("/app", "/composer", factory.Build.Logger, Config{CommandTimeout: time.Minute})
+			Expect(comp.CommandTimeout).To(Equal(time.Minute))
+		})
 	})
 
 	when("there is a composer.json in the app root", func() {
@@ -123,6 +172,43 @@ func testComposer(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("there are several projects", func() {
+		var appRoot string
+
+		it.Before(func() {
+			appRoot = factory.Build.Application.Root
+			test.WriteFile(t, filepath.Join(appRoot, ComposerJSON), "{}")
+			test.WriteFile(t, filepath.Join(appRoot, "worker", ComposerJSON), "{}")
+		})
+
+		it("finds the composer.json of every project in order", func() {
+			paths, err := FindProjects(appRoot, Config{Projects: []string{"worker", "./composer.json"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{filepath.Join(appRoot, "worker", ComposerJSON), filepath.Join(appRoot, ComposerJSON)}))
+		})
+
+		it("falls back to a single project", func() {
+			paths, err := FindProjects(appRoot, Config{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{filepath.Join(appRoot, ComposerJSON)}))
+		})
+
+		it("rejects missing projects", func() {
+			_, err := FindProjects(appRoot, Config{Projects: []string{"tools"}})
+			Expect(err).To(MatchError(`no "composer.json" found for project "tools" in BP_COMPOSER_PROJECTS`))
+		})
+
+		it("rejects projects outside of the app root", func() {
+			_, err := FindProjects(appRoot, Config{Projects: []string{"../other"}})
+			Expect(err).To(MatchError(`project "../other" in BP_COMPOSER_PROJECTS is outside of the app root`))
+		})
+
+		it("rejects duplicate projects", func() {
+			_, err := FindProjects(appRoot, Config{Projects: []string{"worker", "worker/"}})
+			Expect(err).To(MatchError(`project "worker/" is listed more than once in BP_COMPOSER_PROJECTS`))
+		})
+	})
+
 	when("there is a buildpack.yml", func() {
 		it("loads and parses with defaults", func() {
 			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "buildpack.yml"), `{"composer": {"json_path": "subdir"}}`)
@@ -0,0 +1,464 @@
+package composer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+)
+
+const (
+	SourceDefault       = "default"
+	SourceBuildpackYAML = "buildpack.yml"
+	SourceEnvironment   = "environment"
+
+	VersionEnv              = "BP_COMPOSER_VERSION"
+	InstallOptionsEnv       = "BP_COMPOSER_INSTALL_OPTIONS"
+	VendorDirectoryEnv      = "COMPOSER_VENDOR_DIR"
+	JsonPathEnv             = "COMPOSER"
+	GlobalInstallOptionsEnv = "BP_COMPOSER_GLOBAL_INSTALL_OPTIONS"
+	LockValidationEnv       = "BP_COMPOSER_LOCK_VALIDATION"
+	CacheMaxSizeEnv         = "BP_COMPOSER_CACHE_MAX_SIZE"
+	ClearCacheEnv           = "BP_COMPOSER_CLEAR_CACHE"
+	ScriptsEnv              = "BP_COMPOSER_SCRIPTS"
+	AutoloaderEnv           = "BP_COMPOSER_AUTOLOADER"
+	LicensePolicyEnv        = "BP_COMPOSER_LICENSE_POLICY"
+	LicenseAllowEnv         = "BP_COMPOSER_LICENSE_ALLOW"
+	LicenseDenyEnv          = "BP_COMPOSER_LICENSE_DENY"
+	AuditAdvisoriesEnv      = "BP_COMPOSER_AUDIT_ADVISORIES"
+	AuditWarnSeverityEnv    = "BP_COMPOSER_AUDIT_WARN_SEVERITY"
+	AuditFailSeverityEnv    = "BP_COMPOSER_AUDIT_FAIL_SEVERITY"
+	ProjectsEnv             = "BP_COMPOSER_PROJECTS"
+	ArtifactsEnv            = "BP_COMPOSER_ARTIFACTS"
+	MirrorEnv               = "BP_COMPOSER_MIRROR"
+	DisablePackagistEnv     = "BP_COMPOSER_DISABLE_PACKAGIST"
+	GitHubURLEnv            = "BP_COMPOSER_GITHUB_URL"
+	GitLabURLEnv            = "BP_COMPOSER_GITLAB_URL"
+	SkipTokenCheckEnv       = "BP_COMPOSER_SKIP_TOKEN_CHECK"
+	OutputEnv               = "BP_COMPOSER_OUTPUT"
+	TimeoutEnv              = "BP_COMPOSER_TIMEOUT"
+	CommandTimeoutEnv       = "BP_COMPOSER_COMMAND_TIMEOUT"
+	PHPExtensionsEnv        = "BP_COMPOSER_PHP_EXTENSIONS"
+
+	// PHPIniEnvPrefix starts the environment variables that set php.ini directives for Composer, e.g.
+	// BP_COMPOSER_PHP_INI_MEMORY_LIMIT. A double underscore stands for a dot, as in BP_COMPOSER_PHP_INI_DATE__TIMEZONE.
+	PHPIniEnvPrefix = "BP_COMPOSER_PHP_INI_"
+
+	DefaultVendorDirectory = "vendor"
+	DefaultInstallOption   = "--no-dev"
+	DefaultLicensePolicy   = "composer-licenses.yml"
+	DefaultGitHubURL       = "https://github.com"
+	DefaultGitLabURL       = "https://gitlab.com"
+
+	LockValidationWarn = "warn"
+	LockValidationFail = "fail"
+	LockValidationSkip = "skip"
+
+	ScriptsAll  = "all"
+	ScriptsNone = "none"
+
+	AutoloaderDefault               = "default"
+	AutoloaderOptimized             = "optimized"
+	AutoloaderClassmapAuthoritative = "classmap-authoritative"
+	AutoloaderAPCu                  = "apcu"
+
+	OutputPlain      = "plain"
+	OutputStructured = "structured"
+)
+
+// Config holds the effective Composer configuration. Each value is resolved from environment variables first, then
+// from buildpack.yml and finally from the buildpack defaults. Sources records where each value came from.
+type Config struct {
+	Version          string
+	InstallOptions   []string
+	VendorDirectory  string
+	JsonPath         string
+	InstallGlobal    []string
+	LockValidation   string
+	CacheMaxSize     int64
+	ClearCache       bool
+	Scripts          []string
+	Autoloader       string
+	LicensePolicy    string
+	LicenseAllow     []string
+	LicenseDeny      []string
+	AuditAdvisories  string
+	AuditWarn        string
+	AuditFail        string
+	Projects         []string
+	Artifacts        string
+	Mirror           string
+	DisablePackagist bool
+	GitHubURL        string
+	GitLabURL        string
+	SkipTokenCheck   bool
+	Output           string
+	Timeout          time.Duration
+	CommandTimeout   time.Duration
+	PHPExtensions    []string
+	PHPIni           map[string]string
+	Sources          map[string]string
+}
+
+// LoadConfig resolves the Composer configuration for the application
+func LoadConfig(appRoot string) (Config, error) {
+	buildpackYAML, err := loadBuildpackYAML(appRoot)
+	if err != nil {
+		return Config{}, err
+	}
+	yml := buildpackYAML.Composer
+
+	cfg := Config{Sources: map[string]string{}}
+
+	cfg.Version = cfg.resolveString(VersionEnv, yml.Version, "")
+	cfg.InstallOptions = cfg.resolveList(InstallOptionsEnv, yml.InstallOptions, []string{DefaultInstallOption})
+	cfg.VendorDirectory = cfg.resolveString(VendorDirectoryEnv, yml.VendorDirectory, DefaultVendorDirectory)
+	cfg.JsonPath = cfg.resolveString(JsonPathEnv, yml.JsonPath, "")
+	cfg.InstallGlobal = cfg.resolveList(GlobalInstallOptionsEnv, yml.InstallGlobal, nil)
+	cfg.LockValidation = cfg.resolveString(LockValidationEnv, "", LockValidationWarn)
+
+	// COMPOSER names the composer.json file itself, while json_path names its directory
+	if cfg.Sources[JsonPathEnv] == SourceEnvironment && filepath.Base(cfg.JsonPath) == ComposerJSON {
+		cfg.JsonPath = filepath.Dir(cfg.JsonPath)
+	}
+
+	if cfg.CacheMaxSize, err = parseSize(cfg.resolveString(CacheMaxSizeEnv, "", "0")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", CacheMaxSizeEnv, err)
+	}
+
+	if cfg.ClearCache, err = strconv.ParseBool(cfg.resolveString(ClearCacheEnv, "", "false")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", ClearCacheEnv, err)
+	}
+
+	cfg.Scripts = splitList(cfg.resolveString(ScriptsEnv, "", ScriptsAll))
+	for _, event := range cfg.Scripts {
+		if (event == ScriptsAll || event == ScriptsNone) && len(cfg.Scripts) > 1 {
+			return Config{}, fmt.Errorf("invalid %s %q, %s and %s cannot be combined with event names", ScriptsEnv, strings.Join(cfg.Scripts, ","), ScriptsAll, ScriptsNone)
+		}
+	}
+
+	switch cfg.LockValidation {
+	case LockValidationWarn, LockValidationFail, LockValidationSkip:
+	default:
+		return Config{}, fmt.Errorf("invalid %s %q, must be one of: %s, %s, %s", LockValidationEnv, cfg.LockValidation, LockValidationWarn, LockValidationFail, LockValidationSkip)
+	}
+
+	cfg.LicensePolicy = cfg.resolveString(LicensePolicyEnv, "", DefaultLicensePolicy)
+	cfg.LicenseAllow = splitList(cfg.resolveString(LicenseAllowEnv, "", ""))
+	cfg.LicenseDeny = splitList(cfg.resolveString(LicenseDenyEnv, "", ""))
+
+	cfg.AuditAdvisories = cfg.resolveString(AuditAdvisoriesEnv, "", "")
+	if cfg.AuditWarn, err = parseThreshold(cfg.resolveString(AuditWarnSeverityEnv, "", "low")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", AuditWarnSeverityEnv, err)
+	}
+	if cfg.AuditFail, err = parseThreshold(cfg.resolveString(AuditFailSeverityEnv, "", "none")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", AuditFailSeverityEnv, err)
+	}
+
+	cfg.Projects = splitList(cfg.resolveString(ProjectsEnv, "", ""))
+	cfg.Artifacts = cfg.resolveString(ArtifactsEnv, "", "")
+
+	cfg.Mirror = cfg.resolveString(MirrorEnv, "", "")
+	cfg.GitHubURL = strings.TrimSuffix(cfg.resolveString(GitHubURLEnv, "", DefaultGitHubURL), "/")
+	cfg.GitLabURL = strings.TrimSuffix(cfg.resolveString(GitLabURLEnv, "", DefaultGitLabURL), "/")
+	for env, value := range map[string]string{MirrorEnv: cfg.Mirror, GitHubURLEnv: cfg.GitHubURL, GitLabURLEnv: cfg.GitLabURL} {
+		if value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return Config{}, fmt.Errorf("invalid %s %q, must be an http or https URL", env, value)
+		}
+	}
+
+	if cfg.SkipTokenCheck, err = strconv.ParseBool(cfg.resolveString(SkipTokenCheckEnv, "", "false")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", SkipTokenCheckEnv, err)
+	}
+
+	if cfg.DisablePackagist, err = strconv.ParseBool(cfg.resolveString(DisablePackagistEnv, "", "false")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", DisablePackagistEnv, err)
+	}
+
+	cfg.Output = cfg.resolveString(OutputEnv, "", OutputPlain)
+	if cfg.Output != OutputPlain && cfg.Output != OutputStructured {
+		return Config{}, fmt.Errorf("invalid %s %q, must be one of: %s, %s", OutputEnv, cfg.Output, OutputPlain, OutputStructured)
+	}
+
+	if cfg.Timeout, err = parseTimeout(cfg.resolveString(TimeoutEnv, "", "0")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", TimeoutEnv, err)
+	}
+	if cfg.CommandTimeout, err = parseTimeout(cfg.resolveString(CommandTimeoutEnv, "", "0")); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", CommandTimeoutEnv, err)
+	}
+
+	cfg.PHPExtensions = splitList(cfg.resolveString(PHPExtensionsEnv, "", ""))
+	if cfg.PHPIni, err = cfg.resolvePHPIni(); err != nil {
+		return Config{}, err
+	}
+
+	cfg.Autoloader = cfg.resolveString(AutoloaderEnv, "", AutoloaderDefault)
+	switch cfg.Autoloader {
+	case AutoloaderDefault, AutoloaderOptimized, AutoloaderClassmapAuthoritative, AutoloaderAPCu:
+	default:
+		return Config{}, fmt.Errorf("invalid %s %q, must be one of: %s, %s, %s, %s", AutoloaderEnv, cfg.Autoloader, AutoloaderDefault, AutoloaderOptimized, AutoloaderClassmapAuthoritative, AutoloaderAPCu)
+	}
+	if Contains(cfg.InstallOptions, "--no-autoloader") && cfg.Autoloader != AutoloaderDefault {
+		return Config{}, fmt.Errorf("invalid %s %q, no autoloader is generated with --no-autoloader in %s", AutoloaderEnv, cfg.Autoloader, InstallOptionsEnv)
+	}
+
+	return cfg, nil
+}
+
+// Log prints the effective configuration and the source of each value
+func (c Config) Log(logger logger.Logger) {
+	values := map[string]string{
+		VersionEnv:              c.Version,
+		InstallOptionsEnv:       strings.Join(c.InstallOptions, " "),
+		VendorDirectoryEnv:      c.VendorDirectory,
+		JsonPathEnv:             c.JsonPath,
+		GlobalInstallOptionsEnv: strings.Join(c.InstallGlobal, " "),
+		LockValidationEnv:       c.LockValidation,
+		CacheMaxSizeEnv:         strconv.FormatInt(c.CacheMaxSize, 10),
+		ClearCacheEnv:           strconv.FormatBool(c.ClearCache),
+		ScriptsEnv:              strings.Join(c.Scripts, ","),
+		AutoloaderEnv:           c.Autoloader,
+		LicensePolicyEnv:        c.LicensePolicy,
+		LicenseAllowEnv:         strings.Join(c.LicenseAllow, ","),
+		LicenseDenyEnv:          strings.Join(c.LicenseDeny, ","),
+		AuditAdvisoriesEnv:      c.AuditAdvisories,
+		AuditWarnSeverityEnv:    c.AuditWarn,
+		AuditFailSeverityEnv:    c.AuditFail,
+		ProjectsEnv:             strings.Join(c.Projects, ","),
+		ArtifactsEnv:            c.Artifacts,
+		MirrorEnv:               RedactURL(c.Mirror),
+		DisablePackagistEnv:     strconv.FormatBool(c.DisablePackagist),
+		GitHubURLEnv:            c.GitHubURL,
+		GitLabURLEnv:            c.GitLabURL,
+		SkipTokenCheckEnv:       strconv.FormatBool(c.SkipTokenCheck),
+		OutputEnv:               c.Output,
+		TimeoutEnv:              c.Timeout.String(),
+		CommandTimeoutEnv:       c.CommandTimeout.String(),
+		PHPExtensionsEnv:        strings.Join(c.PHPExtensions, ","),
+	}
+	for directive, value := range c.PHPIni {
+		values[PHPIniEnv(directive)] = value
+	}
+
+	var keys []string
+	for key := range c.Sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	logger.Header("Composer configuration")
+	for _, key := range keys {
+		logger.Body("%s=%q (from %s)", key, values[key], c.Sources[key])
+	}
+}
+
+// RunsScripts reports whether the scripts policy allows the scripts of a Composer event to run. Passing --no-scripts
+// in the install options disables all scripts.
+func (c Config) RunsScripts(event string) bool {
+	if Contains(c.InstallOptions, "--no-scripts") {
+		return false
+	}
+
+	for _, allowed := range c.Scripts {
+		if allowed == ScriptsAll || allowed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AutoloaderOptions returns the `composer dump-autoload` options for the autoloader mode. Classmap-authoritative
+// and APCu autoloaders are optimized as well, as Composer implies.
+func (c Config) AutoloaderOptions() []string {
+	switch c.Autoloader {
+	case AutoloaderOptimized:
+		return []string{"--optimize"}
+	case AutoloaderClassmapAuthoritative:
+		return []string{"--optimize", "--classmap-authoritative"}
+	case AutoloaderAPCu:
+		return []string{"--optimize", "--apcu"}
+	default:
+		return nil
+	}
+}
+
+// Deadline returns the time by which all Composer commands of a build started at start must have finished, or the zero
+// time when BP_COMPOSER_TIMEOUT is not set
+func (c Config) Deadline(start time.Time) time.Time {
+	if c.Timeout == 0 {
+		return time.Time{}
+	}
+	return start.Add(c.Timeout)
+}
+
+func (c *Config) resolveString(env, yamlValue, defaultValue string) string {
+	if value, ok := os.LookupEnv(env); ok && value != "" {
+		c.Sources[env] = SourceEnvironment
+		return value
+	}
+
+	if yamlValue != "" {
+		c.Sources[env] = SourceBuildpackYAML
+		return yamlValue
+	}
+
+	c.Sources[env] = SourceDefault
+	return defaultValue
+}
+
+// resolvePHPIni collects the php.ini directives set through BP_COMPOSER_PHP_INI_* environment variables
+func (c *Config) resolvePHPIni() (map[string]string, error) {
+	ini := map[string]string{}
+
+	for _, entry := range os.Environ() {
+		env, value := entry, ""
+		if i := strings.Index(entry, "="); i >= 0 {
+			env, value = entry[:i], entry[i+1:]
+		}
+		if !strings.HasPrefix(env, PHPIniEnvPrefix) || value == "" {
+			continue
+		}
+
+		directive := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(env, PHPIniEnvPrefix), "__", "."))
+		if !phpIniDirective.MatchString(directive) {
+			return nil, fmt.Errorf("invalid %s, %q is not a php.ini directive", env, directive)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid %s, the value must be a single line", env)
+		}
+
+		switch directive {
+		case "memory_limit":
+			if !phpMemoryLimit.MatchString(value) {
+				return nil, fmt.Errorf("invalid %s %q, must be -1 or a size such as 2G", env, value)
+			}
+		case "max_execution_time":
+			if seconds, err := strconv.Atoi(value); err != nil || seconds < 0 {
+				return nil, fmt.Errorf("invalid %s %q, must be a number of seconds", env, value)
+			}
+		}
+
+		ini[directive] = value
+		c.Sources[env] = SourceEnvironment
+	}
+
+	return ini, nil
+}
+
+// PHPIniEnv returns the environment variable that sets a php.ini directive for Composer
+func PHPIniEnv(directive string) string {
+	return PHPIniEnvPrefix + strings.ToUpper(strings.ReplaceAll(directive, ".", "__"))
+}
+
+// an environment variable that is set but empty clears the list, e.g. to install dev dependencies
+func (c *Config) resolveList(env string, yamlValue, defaultValue []string) []string {
+	if value, ok := os.LookupEnv(env); ok {
+		c.Sources[env] = SourceEnvironment
+		return strings.Fields(value)
+	}
+
+	if yamlValue != nil {
+		c.Sources[env] = SourceBuildpackYAML
+		return yamlValue
+	}
+
+	c.Sources[env] = SourceDefault
+	return defaultValue
+}
+
+// splitList splits a comma or whitespace separated list, returning nil for an empty list
+func splitList(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// Contains reports whether values contains value, e.g. an option in the install options
+func Contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL hides the password of a URL, which mirrors and proxies may require
+func RedactURL(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.User == nil {
+		return value
+	}
+
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), "redacted")
+	}
+	return parsed.String()
+}
+
+// parseSize parses a size in bytes with an optional K, M or G suffix, e.g. 512M
+func parseSize(size string) (int64, error) {
+	multiplier := int64(1)
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1 << 30
+	}
+
+	parsed, err := strconv.ParseInt(strings.TrimRight(value, "KMG"), 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%q is not a valid size", size)
+	}
+
+	return parsed * multiplier, nil
+}
+
+var (
+	phpIniDirective = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_]+)*$`)
+	phpMemoryLimit  = regexp.MustCompile(`^(-1|[0-9]+[KMGkmg]?)$`)
+)
+
+// auditThresholds are the severity thresholds of the audit in increasing order. The threshold "none" is never reached.
+var auditThresholds = []string{"none", "low", "medium", "high", "critical"}
+
+// parseThreshold validates a severity threshold of the audit
+func parseThreshold(threshold string) (string, error) {
+	threshold = strings.ToLower(strings.TrimSpace(threshold))
+	for _, valid := range auditThresholds {
+		if threshold == valid {
+			return threshold, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid severity %q, must be one of: %s", threshold, strings.Join(auditThresholds, ", "))
+}
+
+// parseTimeout parses a duration such as 90s or 15m, where zero means no timeout
+func parseTimeout(timeout string) (time.Duration, error) {
+	parsed, err := time.ParseDuration(strings.TrimSpace(timeout))
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%q is not a valid duration", timeout)
+	}
+	return parsed, nil
+}
@@ -1,8 +1,11 @@
 package composer
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/cloudfoundry/libcfbuildpack/build"
 	"github.com/cloudfoundry/libcfbuildpack/helper"
@@ -10,10 +13,17 @@ import (
 	"github.com/paketo-buildpacks/php-web/config"
 )
 
+// ZendExtensions are loaded with zend_extension instead of extension
+var ZendExtensions = map[string]bool{
+	"opcache": true,
+	"xdebug":  true,
+}
+
 type Contributor struct {
 	ComposerLayer     layers.DependencyLayer
 	PhpLayer          layers.Layer
 	buildContribution bool
+	config            Config
 }
 
 func NewContributor(builder build.Build) (Contributor, bool, error) {
@@ -32,9 +42,15 @@ func NewContributor(builder build.Build) (Contributor, bool, error) {
 		return Contributor{}, false, err
 	}
 
+	cfg, err := LoadConfig(builder.Application.Root)
+	if err != nil {
+		return Contributor{}, false, err
+	}
+
 	contributor := Contributor{
 		ComposerLayer: builder.Layers.DependencyLayer(dep),
 		PhpLayer:      builder.Layers.Layer("php"),
+		config:        cfg,
 	}
 
 	if _, ok := plan.Metadata["build"]; ok {
@@ -83,5 +99,53 @@ func (n Contributor) writePhpIni() error {
 		return err
 	}
 
-	return nil
+	return n.appendPhpIniOverrides(phpIniPath, phpIniCfg.Extensions)
+}
+
+// appendPhpIniOverrides adds the extensions from BP_COMPOSER_PHP_EXTENSIONS and the directives from
+// BP_COMPOSER_PHP_INI_* to the php.ini of Composer. They follow the template, so that they take precedence over its
+// defaults, and never reach the php.ini of the application.
+func (n Contributor) appendPhpIniOverrides(phpIniPath string, loaded []string) error {
+	buf := bytes.Buffer{}
+
+	seen := map[string]bool{}
+	for _, extension := range loaded {
+		seen[extension] = true
+	}
+
+	for _, extension := range n.config.PHPExtensions {
+		if seen[extension] {
+			continue
+		}
+		seen[extension] = true
+
+		directive := "extension"
+		if ZendExtensions[extension] {
+			directive = "zend_extension"
+		}
+		buf.WriteString(fmt.Sprintf("%s = %s.so\n", directive, extension))
+	}
+
+	var directives []string
+	for directive := range n.config.PHPIni {
+		directives = append(directives, directive)
+	}
+	sort.Strings(directives)
+
+	for _, directive := range directives {
+		buf.WriteString(fmt.Sprintf("%s = %s\n", directive, n.config.PHPIni[directive]))
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(phpIniPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "\n; Composer settings from %s and %s*\n%s", PHPExtensionsEnv, PHPIniEnvPrefix, buf.String())
+	return err
 }
@@ -73,5 +73,34 @@ func testContributor(t *testing.T, when spec.G, it spec.S) {
 			Expect(string(ini)).To(ContainSubstring("extension = openssl.so"))
 			Expect(string(ini)).To(ContainSubstring("extension = zlib.so"))
 		})
+
+		it("adds the PHP settings for Composer to its php.ini", func() {
+			f := test.NewBuildFactory(t)
+			f.AddPlan(buildpackplan.Plan{Name: composer.Dependency})
+			f.AddDependency(composer.Dependency, stubComposerFixture)
+
+			Expect(os.Setenv(composer.PHPExtensionsEnv, "zlib,gmp,xdebug")).To(Succeed())
+			Expect(os.Setenv(composer.PHPIniEnv("memory_limit"), "-1")).To(Succeed())
+			Expect(os.Setenv(composer.PHPIniEnv("max_execution_time"), "0")).To(Succeed())
+			defer func() {
+				for _, env := range []string{composer.PHPExtensionsEnv, composer.PHPIniEnv("memory_limit"), composer.PHPIniEnv("max_execution_time")} {
+					Expect(os.Unsetenv(env)).To(Succeed())
+				}
+			}()
+
+			composerDep, _, err := composer.NewContributor(f.Build)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composerDep.Contribute()).To(Succeed())
+
+			ini, err := ioutil.ReadFile(filepath.Join(f.Build.Layers.Layer(composer.Dependency).Root, "composer-php.ini"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(ini)).To(HaveSuffix(`
+; Composer settings from BP_COMPOSER_PHP_EXTENSIONS and BP_COMPOSER_PHP_INI_*
+extension = gmp.so
+zend_extension = xdebug.so
+max_execution_time = 0
+memory_limit = -1
+`))
+		})
 	})
 }
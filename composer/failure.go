@@ -0,0 +1,153 @@
+package composer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/paketo-buildpacks/php-composer/runner"
+)
+
+// Exit codes of the build for classified Composer failures. They follow the failure codes 102 to 106 of cmd/build.
+const (
+	FailureResolutionCode   = 107
+	FailureExtensionCode    = 108
+	FailureAuthCode         = 109
+	FailureNetworkCode      = 110
+	FailureMemoryCode       = 111
+	FailureLockOutdatedCode = 112
+)
+
+// excerptLines is how many lines of Composer output a Failure shows, starting at the line that classified it
+const excerptLines = 10
+
+// Failure is a Composer failure that was recognized from the output of the command
+type Failure struct {
+	Class   string
+	Code    int
+	Hint    string
+	Excerpt []string
+}
+
+type failureClass struct {
+	class    string
+	code     int
+	patterns []*regexp.Regexp
+
+	// hint returns the remediation for the submatches of the pattern that matched
+	hint func(match []string) string
+}
+
+// failureClasses are tried in order, as Composer reports, for example, a missing extension as an incompatible lock
+// file as well
+var failureClasses = []failureClass{
+	{
+		class: "Composer ran out of memory",
+		code:  FailureMemoryCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`Allowed memory size of \d+ bytes exhausted`),
+			regexp.MustCompile(`(?i)out of memory`),
+		},
+		hint: func([]string) string {
+			return "Raise the PHP memory limit for Composer with BP_COMPOSER_PHP_INI_MEMORY_LIMIT, e.g. to 2G or -1 for no limit, or give the build more memory"
+		},
+	},
+	{
+		class: "Composer could not authenticate against a repository",
+		code:  FailureAuthCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`HTTP/[0-9.]+ 40[13]`),
+			regexp.MustCompile(`(?i)(invalid|bad) credentials|could not authenticate|authentication required|invalid access token`),
+		},
+		hint: func([]string) string {
+			return "Check the credentials in COMPOSER_AUTH, the composer-auth bindings and the VCS tokens, and that they grant access to the repository"
+		},
+	},
+	{
+		class: "Composer could not reach a repository",
+		code:  FailureNetworkCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)could not resolve host|php_network_getaddresses|failed to connect to|connection (timed out|refused|reset)`),
+			regexp.MustCompile(`(?i)curl error (6|7|28|35|60)\b|SSL certificate problem|network disabled`),
+		},
+		hint: func([]string) string {
+			return "Check that the build can reach the repositories, the HTTPS_PROXY setting and BP_COMPOSER_MIRROR, or install the packages offline from a composer-artifacts binding"
+		},
+	},
+	{
+		class: "A PHP extension required by the packages is missing",
+		code:  FailureExtensionCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`requires (?:PHP extension )?ext-([\w-]+)\b.*(?:it is missing from your system|it has the wrong version)`),
+			regexp.MustCompile(`the requested PHP extension ([\w-]+) is missing from your system`),
+		},
+		hint: func(match []string) string {
+			return fmt.Sprintf("Require ext-%s in composer.json so that the buildpack enables it, or use a PHP version that provides it", match[1])
+		},
+	},
+	{
+		class: "composer.lock is out of date",
+		code:  FailureLockOutdatedCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`lock file is not up to date|lock file does not contain a compatible set of packages|is not present in the lock file`),
+		},
+		hint: func([]string) string {
+			return "Run `composer update` locally and commit the updated composer.lock"
+		},
+	},
+	{
+		class: "Composer could not resolve the dependencies",
+		code:  FailureResolutionCode,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`Your requirements could not be resolved to an installable set of packages`),
+			regexp.MustCompile(`Could not find (?:a matching version of )?package`),
+		},
+		hint: func([]string) string {
+			return "Check the version constraints in composer.json against each other and against the PHP version of the build"
+		},
+	},
+}
+
+// ClassifyFailure recognizes common Composer failures from the output of the failed command
+func ClassifyFailure(err error) (Failure, bool) {
+	var commandErr runner.CommandError
+	if !errors.As(err, &commandErr) {
+		return Failure{}, false
+	}
+
+	for _, class := range failureClasses {
+		for i, line := range commandErr.Output {
+			for _, pattern := range class.patterns {
+				match := pattern.FindStringSubmatch(line)
+				if match == nil {
+					continue
+				}
+
+				end := i + excerptLines
+				if end > len(commandErr.Output) {
+					end = len(commandErr.Output)
+				}
+
+				return Failure{
+					Class:   class.class,
+					Code:    class.code,
+					Hint:    class.hint(match),
+					Excerpt: commandErr.Output[i:end],
+				}, true
+			}
+		}
+	}
+
+	return Failure{}, false
+}
+
+// Log prints the failure with its remediation and the Composer output that classified it
+func (f Failure) Log(logger logger.Logger) {
+	logger.BodyError("%s", f.Class)
+	logger.Body("%s", f.Hint)
+	logger.Body("Composer output:")
+	for _, line := range f.Excerpt {
+		logger.Body("  %s", line)
+	}
+}
@@ -0,0 +1,83 @@
+package composer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	bp "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/php-composer/runner"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitFailure(t *testing.T) {
+	spec.Run(t, "Failure", testFailure, spec.Report(report.Terminal{}))
+}
+
+func testFailure(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	failed := func(output ...string) error {
+		return fmt.Errorf("project app: %w", runner.CommandError{Command: "php composer.phar install", Output: output, Err: &exec.ExitError{}})
+	}
+
+	it("classifies common failures", func() {
+		for output, code := range map[string]int{
+			"Your requirements could not be resolved to an installable set of packages.":                                             FailureResolutionCode,
+			"    - Root composer.json requires ext-gmp * but it is missing from your system. Install or enable PHP's gmp extension.": FailureExtensionCode,
+			"  The 'https://repo.example.com/packages.json' URL could not be accessed (HTTP/2 401 ): Unauthorized":                   FailureAuthCode,
+			"curl error 6 while downloading https://repo.packagist.org/packages.json: Could not resolve host: repo.packagist.org":    FailureNetworkCode,
+			"PHP Fatal error:  Allowed memory size of 1610612736 bytes exhausted (tried to allocate 4096 bytes)":                     FailureMemoryCode,
+			"Warning: The lock file is not up to date with the latest changes in composer.json.":                                     FailureLockOutdatedCode,
+		} {
+			failure, ok := ClassifyFailure(failed("Installing dependencies from lock file", output))
+			Expect(ok).To(BeTrue(), output)
+			Expect(failure.Code).To(Equal(code), output)
+			Expect(failure.Excerpt).To(Equal([]string{output}))
+		}
+	})
+
+	it("prefers a missing extension over an incompatible lock file", func() {
+		failure, ok := ClassifyFailure(failed(
+			"Your lock file does not contain a compatible set of packages. Please run composer update.",
+			"",
+			"  Problem 1",
+			"    - laminas/laminas-ldap is locked to version 2.10.0 and an update of this package was not requested.",
+			"    - laminas/laminas-ldap 2.10.0 requires ext-ldap * -> it is missing from your system. Install or enable PHP's ldap extension.",
+		))
+		Expect(ok).To(BeTrue())
+		Expect(failure.Code).To(Equal(FailureExtensionCode))
+		Expect(failure.Hint).To(ContainSubstring("Require ext-ldap in composer.json"))
+		Expect(failure.Excerpt).To(HaveLen(1))
+	})
+
+	it("does not classify unknown failures", func() {
+		_, ok := ClassifyFailure(failed("Something else went wrong"))
+		Expect(ok).To(BeFalse())
+
+		_, ok = ClassifyFailure(errors.New("exit status 1"))
+		Expect(ok).To(BeFalse())
+
+		_, ok = ClassifyFailure(nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	it("logs the class, the hint and the excerpt", func() {
+		failure, ok := ClassifyFailure(failed("Your requirements could not be resolved to an installable set of packages.", "", "  Problem 1"))
+		Expect(ok).To(BeTrue())
+
+		buf := bytes.NewBuffer(nil)
+		failure.Log(logger.Logger{Logger: bp.NewLogger(buf, buf)})
+		Expect(buf.String()).To(ContainSubstring("Composer could not resolve the dependencies"))
+		Expect(buf.String()).To(ContainSubstring("Check the version constraints"))
+		Expect(buf.String()).To(ContainSubstring("Composer output:\n      Your requirements could not be resolved"))
+		Expect(buf.String()).To(ContainSubstring("        Problem 1\n"))
+	})
+}
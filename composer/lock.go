@@ -0,0 +1,57 @@
+package composer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// ValidateLock compares the content-hash stored in the composer.lock next to composerJSONPath with the content-hash
+// of composer.json. Depending on the policy, an outdated lock file is logged as a warning or returned as an error.
+func ValidateLock(composerJSONPath, policy string, logger logger.Logger) error {
+	if policy == LockValidationSkip {
+		return nil
+	}
+
+	lockPath := filepath.Join(filepath.Dir(composerJSONPath), ComposerLock)
+	if exists, err := helper.FileExists(lockPath); err != nil || !exists {
+		return err
+	}
+
+	lock, err := manifest.ReadLock(lockPath)
+	if err != nil {
+		return err
+	}
+
+	// lock files written by very old versions of Composer have no content-hash to compare with
+	if lock.ContentHash == "" {
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(composerJSONPath)
+	if err != nil {
+		return err
+	}
+
+	contentHash, err := manifest.ContentHash(buf)
+	if err != nil {
+		return fmt.Errorf("unable to compute the content-hash of %s: %w", composerJSONPath, err)
+	}
+
+	if contentHash == lock.ContentHash {
+		return nil
+	}
+
+	err = fmt.Errorf("%s is not up to date with the latest changes in %s (content-hash %s, expected %s). "+
+		"Run `composer update` and commit the updated %s", ComposerLock, ComposerJSON, lock.ContentHash, contentHash, ComposerLock)
+	if policy == LockValidationFail {
+		return err
+	}
+
+	logger.BodyWarning("WARNING: %s", err)
+	return nil
+}
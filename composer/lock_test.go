@@ -0,0 +1,79 @@
+package composer
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	bp "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitLock(t *testing.T) {
+	spec.Run(t, "Lock", testLock, spec.Report(report.Terminal{}))
+}
+
+func testLock(t *testing.T, when spec.G, it spec.S) {
+	var (
+		composerJSONPath string
+		composerLockPath string
+		buf              *bytes.Buffer
+		log              logger.Logger
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+
+		appRoot := test.NewBuildFactory(t).Build.Application.Root
+		composerJSONPath = filepath.Join(appRoot, ComposerJSON)
+		composerLockPath = filepath.Join(appRoot, ComposerLock)
+		test.WriteFile(t, composerJSONPath, `{"name": "cloudfoundry/composer_app", "require": {"monolog/monolog": "^1.24"}}`)
+
+		buf = bytes.NewBuffer(nil)
+		log = logger.Logger{Logger: bp.NewLogger(buf, buf)}
+	})
+
+	when("the lock file is up to date", func() {
+		it("passes", func() {
+			test.WriteFile(t, composerLockPath, `{"content-hash": "f058889b47c3a5fb76e15858a259c5b5"}`)
+
+			Expect(ValidateLock(composerJSONPath, LockValidationFail, log)).To(Succeed())
+			Expect(buf.String()).To(BeEmpty())
+		})
+	})
+
+	when("the lock file is out of date", func() {
+		it.Before(func() {
+			test.WriteFile(t, composerLockPath, `{"content-hash": "0123456789abcdef0123456789abcdef"}`)
+		})
+
+		it("warns by default", func() {
+			Expect(ValidateLock(composerJSONPath, LockValidationWarn, log)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("WARNING: composer.lock is not up to date with the latest changes in composer.json"))
+			Expect(buf.String()).To(ContainSubstring("Run `composer update`"))
+		})
+
+		it("fails when configured to", func() {
+			err := ValidateLock(composerJSONPath, LockValidationFail, log)
+			Expect(err).To(MatchError(ContainSubstring("content-hash 0123456789abcdef0123456789abcdef, expected f058889b47c3a5fb76e15858a259c5b5")))
+		})
+
+		it("does nothing when skipped", func() {
+			Expect(ValidateLock(composerJSONPath, LockValidationSkip, log)).To(Succeed())
+			Expect(buf.String()).To(BeEmpty())
+		})
+	})
+
+	when("there is no lock file or it has no content-hash", func() {
+		it("passes", func() {
+			Expect(ValidateLock(composerJSONPath, LockValidationFail, log)).To(Succeed())
+
+			test.WriteFile(t, composerLockPath, `{"hash": "0123456789abcdef0123456789abcdef"}`)
+			Expect(ValidateLock(composerJSONPath, LockValidationFail, log)).To(Succeed())
+		})
+	})
+}
@@ -0,0 +1,221 @@
+package composer
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// Statuses of a platform requirement, as reported by `composer check-platform-reqs`. Requirements derived from
+// composer.json or composer.lock have no status.
+const (
+	PlatformStatusSuccess = "success"
+	PlatformStatusMissing = "missing"
+	PlatformStatusFailed  = "failed"
+
+	// RootPackage is the name Composer uses for the app itself
+	RootPackage = "__root__"
+
+	// PlatformSourceCheck is the source of requirements parsed from `composer check-platform-reqs`
+	PlatformSourceCheck = "check-platform-reqs"
+)
+
+var (
+	// platformPackage matches the names of platform packages, mirroring Composer's PlatformRepository::PLATFORM_PACKAGE_REGEX
+	platformPackage = regexp.MustCompile(`(?i)^(?:php(?:-64bit|-ipv6|-zts|-debug)?|hhvm|(?:ext|lib)-[a-z0-9](?:[_.-]?[a-z0-9]+)*|composer(?:-(?:plugin|runtime)-api)?)$`)
+
+	checkLine   = regexp.MustCompile(`^(\S+)\s+(\S+)\s*(.*?)\s*\b(success|missing|failed)\b(?:\s+provided by\s+(\S+))?\s*$`)
+	requirerRef = regexp.MustCompile(`(\S+)\s+(?:requires|conflicts)\s+\S+\s+\(([^)]*)\)`)
+)
+
+// PlatformLink is a constraint a package places on a platform package
+type PlatformLink struct {
+	Package    string
+	Constraint string
+}
+
+// PlatformRequirement is a requirement on a platform package like php, php-64bit, ext-*, lib-* or
+// composer-plugin-api
+type PlatformRequirement struct {
+	Name       string
+	RequiredBy []PlatformLink
+	Provided   string
+	ProvidedBy string
+	Status     string
+}
+
+// PlatformRequirements are the platform requirements of an app, sorted by name
+type PlatformRequirements struct {
+	Source       string
+	Requirements []PlatformRequirement
+}
+
+// IsPlatformPackage reports whether a package name refers to a platform package rather than an installable package
+func IsPlatformPackage(name string) bool {
+	return platformPackage.MatchString(name)
+}
+
+// IsExtension reports whether the requirement is on a PHP extension
+func (r PlatformRequirement) IsExtension() bool {
+	return strings.HasPrefix(strings.ToLower(r.Name), "ext-")
+}
+
+// Extension returns the name of the required extension, e.g. pdo for ext-pdo
+func (r PlatformRequirement) Extension() string {
+	return strings.TrimPrefix(strings.ToLower(r.Name), "ext-")
+}
+
+// Constraint returns the constraint a package places on the platform package, or an empty string
+func (r PlatformRequirement) Constraint(pkg string) string {
+	for _, link := range r.RequiredBy {
+		if link.Package == pkg {
+			return link.Constraint
+		}
+	}
+	return ""
+}
+
+// Find returns the requirement on a platform package
+func (p PlatformRequirements) Find(name string) (PlatformRequirement, bool) {
+	for _, requirement := range p.Requirements {
+		if strings.EqualFold(requirement.Name, name) {
+			return requirement, true
+		}
+	}
+	return PlatformRequirement{}, false
+}
+
+// Extensions returns the names of all required extensions, sorted
+func (p PlatformRequirements) Extensions() []string {
+	var extensions []string
+	for _, requirement := range p.Requirements {
+		if requirement.IsExtension() {
+			extensions = append(extensions, requirement.Extension())
+		}
+	}
+	return extensions
+}
+
+// MissingExtensions returns the required extensions that check-platform-reqs reported as missing
+func (p PlatformRequirements) MissingExtensions() []string {
+	extensions := []string{}
+	for _, requirement := range p.Requirements {
+		if requirement.IsExtension() && requirement.Status == PlatformStatusMissing {
+			extensions = append(extensions, requirement.Extension())
+		}
+	}
+	return extensions
+}
+
+// ParsePlatformReqs parses the output of `composer check-platform-reqs`. A requirement is listed once per package
+// placing a constraint on it, so lines are merged by name.
+func ParsePlatformReqs(output string) PlatformRequirements {
+	requirements := platformRequirements{}
+
+	for _, line := range strings.Split(output, "\n") {
+		match := checkLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		requirement := requirements.get(match[1])
+		if match[2] != "n/a" {
+			requirement.Provided = match[2]
+		}
+		requirement.ProvidedBy = match[5]
+
+		// a failed or missing line takes precedence over a successful one
+		if requirement.Status == "" || requirement.Status == PlatformStatusSuccess {
+			requirement.Status = match[4]
+		}
+
+		for _, ref := range requirerRef.FindAllStringSubmatch(match[3], -1) {
+			requirement.link(ref[1], ref[2])
+		}
+	}
+
+	return requirements.sorted(PlatformSourceCheck)
+}
+
+// ReadPlatformRequirements derives the platform requirements from composer.lock or, for apps without a lock file, from
+// composer.json, without running Composer. Requirements from the lock include those of every locked package.
+func ReadPlatformRequirements(composerJSONPath string, dev bool) (PlatformRequirements, error) {
+	requirements := platformRequirements{}
+
+	lockPath := filepath.Join(filepath.Dir(composerJSONPath), ComposerLock)
+	if exists, err := helper.FileExists(lockPath); err != nil {
+		return PlatformRequirements{}, err
+	} else if exists {
+		lock, err := manifest.ReadLock(lockPath)
+		if err != nil {
+			return PlatformRequirements{}, err
+		}
+
+		requirements.add(RootPackage, lock.Platform)
+		packages := lock.Packages
+		if dev {
+			requirements.add(RootPackage, lock.PlatformDev)
+			packages = lock.AllPackages()
+		}
+		for _, pkg := range packages {
+			requirements.add(pkg.Name, pkg.Require)
+		}
+
+		return requirements.sorted(ComposerLock), nil
+	}
+
+	composerJSON, err := manifest.ReadManifest(composerJSONPath)
+	if err != nil {
+		return PlatformRequirements{}, err
+	}
+
+	requirements.add(RootPackage, composerJSON.Require)
+	if dev {
+		requirements.add(RootPackage, composerJSON.RequireDev)
+	}
+
+	return requirements.sorted(ComposerJSON), nil
+}
+
+// platformRequirements collects requirements by lowercase name while parsing
+type platformRequirements map[string]*PlatformRequirement
+
+func (p platformRequirements) get(name string) *PlatformRequirement {
+	key := strings.ToLower(name)
+	if p[key] == nil {
+		p[key] = &PlatformRequirement{Name: name}
+	}
+	return p[key]
+}
+
+func (p platformRequirements) add(pkg string, links manifest.Links) {
+	for name, constraint := range links {
+		if IsPlatformPackage(name) {
+			p.get(name).link(pkg, constraint)
+		}
+	}
+}
+
+func (p platformRequirements) sorted(source string) PlatformRequirements {
+	result := PlatformRequirements{Source: source, Requirements: []PlatformRequirement{}}
+	for _, requirement := range p {
+		sort.SliceStable(requirement.RequiredBy, func(i, j int) bool { return requirement.RequiredBy[i].Package < requirement.RequiredBy[j].Package })
+		result.Requirements = append(result.Requirements, *requirement)
+	}
+	sort.Slice(result.Requirements, func(i, j int) bool { return result.Requirements[i].Name < result.Requirements[j].Name })
+
+	return result
+}
+
+func (r *PlatformRequirement) link(pkg, constraint string) {
+	for _, link := range r.RequiredBy {
+		if link.Package == pkg && link.Constraint == constraint {
+			return
+		}
+	}
+	r.RequiredBy = append(r.RequiredBy, PlatformLink{Package: pkg, Constraint: constraint})
+}
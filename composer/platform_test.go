@@ -0,0 +1,117 @@
+package composer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitPlatform(t *testing.T) {
+	spec.Run(t, "Platform", testPlatform, spec.Report(report.Terminal{}))
+}
+
+func testPlatform(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("parsing check-platform-reqs output", func() {
+		it("parses every requirement", func() {
+			requirements := ParsePlatformReqs(`Checking platform requirements for packages in the vendor dir
+composer-plugin-api  2.1.0                                                   success
+ext-json             7.4.3                                                   success
+ext-mbstring         1.23.1   success provided by symfony/polyfill-mbstring
+ext-pdo              n/a      doctrine/dbal requires ext-pdo (*)             missing
+ext-pdo              n/a      doctrine/orm requires ext-pdo (^7.1)           missing
+lib-icu              64.2                                                    success
+php                  7.4.3    __root__ requires php (^8.0)                   failed
+php-64bit            7.4.3                                                   success
+`)
+			Expect(requirements.Source).To(Equal(PlatformSourceCheck))
+			Expect(requirements.Requirements).To(HaveLen(7))
+
+			pdo, ok := requirements.Find("ext-pdo")
+			Expect(ok).To(BeTrue())
+			Expect(pdo).To(Equal(PlatformRequirement{
+				Name:       "ext-pdo",
+				RequiredBy: []PlatformLink{{Package: "doctrine/dbal", Constraint: "*"}, {Package: "doctrine/orm", Constraint: "^7.1"}},
+				Status:     PlatformStatusMissing,
+			}))
+
+			mbstring, _ := requirements.Find("ext-mbstring")
+			Expect(mbstring.Provided).To(Equal("1.23.1"))
+			Expect(mbstring.ProvidedBy).To(Equal("symfony/polyfill-mbstring"))
+
+			php, _ := requirements.Find("php")
+			Expect(php.Status).To(Equal(PlatformStatusFailed))
+			Expect(php.Provided).To(Equal("7.4.3"))
+			Expect(php.Constraint(RootPackage)).To(Equal("^8.0"))
+
+			Expect(requirements.MissingExtensions()).To(Equal([]string{"pdo"}))
+		})
+	})
+
+	when("reading requirements without running Composer", func() {
+		var composerJSONPath string
+
+		it.Before(func() {
+			composerJSONPath = filepath.Join(test.ScratchDir(t, "platform"), ComposerJSON)
+			test.WriteFile(t, composerJSONPath, `{"require": {"php": ">=7.2", "ext-gd": "*", "monolog/monolog": "^2.0"}, "require-dev": {"ext-xdebug": "*"}}`)
+		})
+
+		it("reads composer.json when there is no lock file", func() {
+			requirements, err := ReadPlatformRequirements(composerJSONPath, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requirements.Source).To(Equal(ComposerJSON))
+			Expect(requirements.Requirements).To(HaveLen(2))
+
+			gd, ok := requirements.Find("ext-gd")
+			Expect(ok).To(BeTrue())
+			Expect(gd.Constraint(RootPackage)).To(Equal("*"))
+			Expect(gd.Status).To(BeEmpty())
+		})
+
+		it("includes the requirements of locked packages", func() {
+			test.WriteFile(t, filepath.Join(filepath.Dir(composerJSONPath), ComposerLock), `{
+	"packages": [{"name": "monolog/monolog", "version": "2.0.1", "require": {"php": ">=7.1", "psr/log": "^1.0", "ext-json": "*"}}],
+	"packages-dev": [{"name": "phpunit/phpunit", "version": "9.0.0", "require": {"ext-dom": "*"}}],
+	"platform": {"php": ">=7.2", "ext-gd": "*"},
+	"platform-dev": {"ext-xdebug": "*"}
+}`)
+
+			requirements, err := ReadPlatformRequirements(composerJSONPath, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requirements.Source).To(Equal(ComposerLock))
+
+			var names []string
+			for _, requirement := range requirements.Requirements {
+				names = append(names, requirement.Name)
+			}
+			Expect(names).To(Equal([]string{"ext-gd", "ext-json", "php"}))
+			Expect(requirements.Extensions()).To(Equal([]string{"gd", "json"}))
+
+			php, _ := requirements.Find("php")
+			Expect(php.RequiredBy).To(Equal([]PlatformLink{{Package: RootPackage, Constraint: ">=7.2"}, {Package: "monolog/monolog", Constraint: ">=7.1"}}))
+
+			requirements, err = ReadPlatformRequirements(composerJSONPath, true)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := requirements.Find("ext-dom")
+			Expect(ok).To(BeTrue())
+			_, ok = requirements.Find("ext-xdebug")
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	it("recognizes platform packages", func() {
+		for _, name := range []string{"php", "php-64bit", "ext-pdo_sqlite", "lib-icu", "composer-plugin-api", "Ext-GD"} {
+			Expect(IsPlatformPackage(name)).To(BeTrue(), name)
+		}
+		for _, name := range []string{"monolog/monolog", "phpunit", "ext-"} {
+			Expect(IsPlatformPackage(name)).To(BeFalse(), name)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package licenses
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+	"gopkg.in/yaml.v2"
+)
+
+// Policy is a list of allowed and denied SPDX license identifiers. An empty allow list allows every license that is
+// not denied.
+type Policy struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Violation is a package that declares no license the policy permits
+type Violation struct {
+	Package  string
+	Version  string
+	Licenses []string
+	Path     []string
+}
+
+var (
+	orOperator  = regexp.MustCompile(`(?i)\s+or\s+`)
+	andOperator = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// ReadPolicy reads a policy file. A missing file is an empty policy.
+func ReadPolicy(path string) (Policy, error) {
+	if exists, err := helper.FileExists(path); err != nil || !exists {
+		return Policy{}, err
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	policy := Policy{}
+	if err := yaml.UnmarshalStrict(buf, &policy); err != nil {
+		return Policy{}, fmt.Errorf("unable to parse license policy %s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+// Empty reports whether the policy permits every license
+func (p Policy) Empty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Permits reports whether a package with the given licenses may be installed. Composer lists alternative licenses, so
+// one permitted alternative is enough. SPDX expressions are flattened: each `or` operand is an alternative and all
+// `and` operands of an alternative must be permitted. Packages without a license are only permitted when there is no
+// allow list.
+func (p Policy) Permits(licenses []string) bool {
+	if len(licenses) == 0 {
+		return len(p.Allow) == 0
+	}
+
+	for _, license := range licenses {
+		expression := strings.NewReplacer("(", " ", ")", " ").Replace(license)
+		for _, alternative := range orOperator.Split(strings.TrimSpace(expression), -1) {
+			if p.permitsAll(andOperator.Split(alternative, -1)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p Policy) permitsAll(licenses []string) bool {
+	for _, license := range licenses {
+		license = strings.TrimSpace(license)
+		if matches(p.Deny, license) || (len(p.Allow) > 0 && !matches(p.Allow, license)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check returns the packages the policy does not permit, together with the path through which they are required
+func (p Policy) Check(packages []manifest.Package, paths map[string][]string) []Violation {
+	var violations []Violation
+	for _, pkg := range packages {
+		if p.Permits(pkg.License) {
+			continue
+		}
+
+		violations = append(violations, Violation{
+			Package:  pkg.Name,
+			Version:  pkg.Version,
+			Licenses: pkg.License,
+			Path:     paths[strings.ToLower(pkg.Name)],
+		})
+	}
+
+	return violations
+}
+
+func (v Violation) String() string {
+	licenses := "no license"
+	if len(v.Licenses) > 0 {
+		licenses = strings.Join(v.Licenses, ", ")
+	}
+
+	path := "composer.json"
+	for _, name := range v.Path {
+		path += " > " + name
+	}
+	if len(v.Path) == 0 {
+		path += " > ... > " + v.Package
+	}
+
+	return fmt.Sprintf("%s %s (%s) required by %s", v.Package, v.Version, licenses, path)
+}
+
+func matches(licenses []string, license string) bool {
+	for _, l := range licenses {
+		if strings.EqualFold(l, license) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package licenses
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitPolicy(t *testing.T) {
+	spec.Run(t, "Policy", testPolicy, spec.Report(report.Terminal{}))
+}
+
+func testPolicy(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("reading a policy file", func() {
+		it("parses the allow and deny lists", func() {
+			path := filepath.Join(test.ScratchDir(t, "policy"), "composer-licenses.yml")
+			test.WriteFile(t, path, "allow: [MIT, BSD-3-Clause]\ndeny:\n  - GPL-3.0-only\n")
+
+			policy, err := ReadPolicy(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal(Policy{Allow: []string{"MIT", "BSD-3-Clause"}, Deny: []string{"GPL-3.0-only"}}))
+		})
+
+		it("returns an empty policy when the file does not exist", func() {
+			policy, err := ReadPolicy(filepath.Join(test.ScratchDir(t, "policy"), "missing.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.Empty()).To(BeTrue())
+		})
+
+		it("rejects unknown keys", func() {
+			path := filepath.Join(test.ScratchDir(t, "policy"), "composer-licenses.yml")
+			test.WriteFile(t, path, "allowed: [MIT]\n")
+
+			_, err := ReadPolicy(path)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse license policy")))
+		})
+	})
+
+	when("evaluating licenses", func() {
+		it("denies listed licenses", func() {
+			policy := Policy{Deny: []string{"GPL-3.0-only"}}
+
+			Expect(policy.Permits([]string{"MIT"})).To(BeTrue())
+			Expect(policy.Permits([]string{"gpl-3.0-only"})).To(BeFalse())
+			Expect(policy.Permits([]string{"GPL-3.0-only", "MIT"})).To(BeTrue())
+			Expect(policy.Permits(nil)).To(BeTrue())
+		})
+
+		it("only allows listed licenses", func() {
+			policy := Policy{Allow: []string{"MIT", "Apache-2.0"}}
+
+			Expect(policy.Permits([]string{"MIT"})).To(BeTrue())
+			Expect(policy.Permits([]string{"LGPL-2.1-only"})).To(BeFalse())
+			Expect(policy.Permits(nil)).To(BeFalse())
+		})
+
+		it("evaluates SPDX expressions", func() {
+			policy := Policy{Allow: []string{"MIT", "Apache-2.0"}}
+
+			Expect(policy.Permits([]string{"(LGPL-2.1-only or MIT)"})).To(BeTrue())
+			Expect(policy.Permits([]string{"MIT AND Apache-2.0"})).To(BeTrue())
+			Expect(policy.Permits([]string{"(MIT and GPL-2.0-only)"})).To(BeFalse())
+		})
+	})
+
+	it("reports violations with their requirement path", func() {
+		policy := Policy{Deny: []string{"GPL-3.0-only"}}
+
+		violations := policy.Check([]manifest.Package{
+			{Name: "monolog/monolog", Version: "2.0.1", License: manifest.StringList{"MIT"}},
+			{Name: "acme/gpl", Version: "1.0.0", License: manifest.StringList{"GPL-3.0-only"}},
+		}, map[string][]string{"acme/gpl": {"monolog/monolog", "acme/gpl"}})
+
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].String()).To(Equal("acme/gpl 1.0.0 (GPL-3.0-only) required by composer.json > monolog/monolog > acme/gpl"))
+	})
+}
@@ -0,0 +1,305 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// contentHashKeys are the composer.json keys Composer includes in the content-hash of composer.lock
+var contentHashKeys = map[string]bool{
+	"name":              true,
+	"version":           true,
+	"require":           true,
+	"require-dev":       true,
+	"conflict":          true,
+	"replace":           true,
+	"provide":           true,
+	"minimum-stability": true,
+	"prefer-stable":     true,
+	"repositories":      true,
+	"extra":             true,
+}
+
+// ContentHash computes the content-hash Composer stores in composer.lock for the given composer.json content. It
+// mirrors Locker::getContentHash, which MD5s the PHP json_encode output of the relevant keys.
+func ContentHash(composerJSON []byte) (string, error) {
+	root, err := decodeOrdered(composerJSON)
+	if err != nil {
+		return "", err
+	}
+
+	content, ok := root.(object)
+	if !ok {
+		return "", fmt.Errorf("composer.json must contain an object")
+	}
+
+	relevant := object{}
+	for _, m := range content {
+		if contentHashKeys[m.key] {
+			relevant = relevant.set(m.key, m.value)
+		}
+	}
+
+	if config, ok := content.get("config").(object); ok {
+		if platform := config.get("platform"); platform != nil {
+			relevant = relevant.set("config", object{{key: "platform", value: platform}})
+		}
+	}
+
+	sort.SliceStable(relevant, func(i, j int) bool { return relevant[i].key < relevant[j].key })
+
+	buf := bytes.Buffer{}
+	encodePHP(&buf, relevant)
+
+	sum := md5.Sum(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// object is a JSON object that keeps the order of its members, like a PHP array
+type object []member
+
+func (o object) get(key string) interface{} {
+	for _, m := range o {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// set replaces the value of an existing key in place, as PHP does for duplicate keys
+func (o object) set(key string, value interface{}) object {
+	for i, m := range o {
+		if m.key == key {
+			o[i].value = value
+			return o
+		}
+	}
+	return append(o, member{key: key, value: value})
+}
+
+func decodeOrdered(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected content after the top-level value")
+	}
+
+	return value, nil
+}
+
+func decodeValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			o := object{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := decodeValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				o = o.set(key.(string), value)
+			}
+			_, err := decoder.Token()
+			return o, err
+		case '[':
+			list := []interface{}{}
+			for decoder.More() {
+				value, err := decodeValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err := decoder.Token()
+			return list, err
+		}
+		return nil, fmt.Errorf("unexpected delimiter %s", t)
+	default:
+		return t, nil
+	}
+}
+
+// encodePHP writes value the way PHP's json_encode does without any flags: slashes and non-ASCII characters are
+// escaped, and PHP arrays that are empty or have sequential keys are written as lists
+func encodePHP(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		encodePHPNumber(buf, v)
+	case string:
+		encodePHPString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodePHP(buf, item)
+		}
+		buf.WriteByte(']')
+	case object:
+		if v.isList() {
+			list := make([]interface{}, len(v))
+			for i, m := range v {
+				list[i] = m.value
+			}
+			encodePHP(buf, list)
+			return
+		}
+
+		buf.WriteByte('{')
+		for i, m := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodePHPString(buf, m.key)
+			buf.WriteByte(':')
+			encodePHP(buf, m.value)
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// isList reports whether PHP would decode the object into an array with the keys 0..n-1
+func (o object) isList() bool {
+	for i, m := range o {
+		if m.key != strconv.Itoa(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodePHPNumber writes a number the way json_decode and json_encode round trip it: integers that fit into 64 bits
+// stay integers, all other numbers become floats written with the fewest digits that round trip, as PHP does with a
+// serialize_precision of -1
+func encodePHPNumber(buf *bytes.Buffer, n json.Number) {
+	if !strings.ContainsAny(n.String(), ".eE") {
+		if i, err := n.Int64(); err == nil {
+			buf.WriteString(strconv.FormatInt(i, 10))
+			return
+		}
+	}
+
+	f, _ := strconv.ParseFloat(n.String(), 64)
+	if math.IsInf(f, 0) {
+		// json_encode fails for floats out of range, Composer has no content-hash to compare with
+		buf.WriteString(n.String())
+		return
+	}
+	if math.Signbit(f) {
+		buf.WriteByte('-')
+		f = -f
+	}
+
+	// the shortest digits of the float and the position of the decimal point, as zend_dtoa returns them
+	mantissa, exponent := splitFloat(f)
+	digits := strings.TrimRight(strings.Replace(mantissa, ".", "", 1), "0")
+	if digits == "" {
+		digits = "0"
+	}
+	point := exponent + 1
+
+	switch {
+	case point < -3 || point > 17:
+		buf.WriteString(digits[:1])
+		buf.WriteByte('.')
+		if len(digits) == 1 {
+			buf.WriteByte('0')
+		} else {
+			buf.WriteString(digits[1:])
+		}
+		fmt.Fprintf(buf, "e%+d", point-1)
+	case point <= 0:
+		buf.WriteString("0.")
+		buf.WriteString(strings.Repeat("0", -point))
+		buf.WriteString(digits)
+	case point >= len(digits):
+		buf.WriteString(digits)
+		buf.WriteString(strings.Repeat("0", point-len(digits)))
+	default:
+		buf.WriteString(digits[:point])
+		buf.WriteByte('.')
+		buf.WriteString(digits[point:])
+	}
+}
+
+// splitFloat returns the shortest decimal mantissa of f, such as 1.5, and its exponent
+func splitFloat(f float64) (string, int) {
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	e := strings.IndexByte(s, 'e')
+	exponent, _ := strconv.Atoi(s[e+1:])
+	return s[:e], exponent
+}
+
+func encodePHPString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '/':
+			buf.WriteString(`\/`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			switch {
+			case r < 0x20:
+				fmt.Fprintf(buf, `\u%04x`, r)
+			case r < utf8.RuneSelf:
+				buf.WriteRune(r)
+			case r > 0xffff:
+				high, low := utf16.EncodeRune(r)
+				fmt.Fprintf(buf, `\u%04x\u%04x`, high, low)
+			default:
+				fmt.Fprintf(buf, `\u%04x`, r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,110 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitContentHash(t *testing.T) {
+	spec.Run(t, "ContentHash", testContentHash, spec.Report(report.Terminal{}))
+}
+
+func testContentHash(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("matches the content-hash Composer writes to composer.lock", func() {
+		hash, err := ContentHash([]byte(`{
+    "name": "cloudfoundry/composer_app",
+    "type": "project",
+    "require": {
+        "monolog/monolog": "^1.24"
+    }
+}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).To(Equal("f058889b47c3a5fb76e15858a259c5b5"))
+	})
+
+	it("ignores keys that are not relevant to dependency resolution", func() {
+		withDescription, err := ContentHash([]byte(`{"description": "a", "require": {"php": ">=7.2"}, "autoload": {"psr-4": {"App\\": "src/"}}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		without, err := ContentHash([]byte(`{"require": {"php": ">=7.2"}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(withDescription).To(Equal(without))
+	})
+
+	it("includes config.platform but no other config", func() {
+		base, err := ContentHash([]byte(`{"require": {"php": ">=7.2"}, "config": {"vendor-dir": "lib"}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		withPlatform, err := ContentHash([]byte(`{"require": {"php": ">=7.2"}, "config": {"platform": {"php": "7.2.5"}}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(base).NotTo(Equal(withPlatform))
+	})
+
+	it("encodes like PHP's json_encode", func() {
+		encode := func(data string) string {
+			value, err := decodeOrdered([]byte(data))
+			Expect(err).NotTo(HaveOccurred())
+
+			buf := bytes.Buffer{}
+			encodePHP(&buf, value)
+			return buf.String()
+		}
+
+		Expect(encode(`{"url": "https://example.com/a"}`)).To(Equal(`{"url":"https:\/\/example.com\/a"}`))
+		Expect(encode(`{"name": "Jürgen 😀 <&>"}`)).To(Equal(`{"name":"J\u00fcrgen \ud83d\ude00 <&>"}`))
+		Expect(encode(`{"require-dev": {}, "extra": {"0": "a", "1": "b"}}`)).To(Equal(`{"require-dev":[],"extra":["a","b"]}`))
+		Expect(encode(`{"a": 1.50, "b": true, "c": null, "a": 2}`)).To(Equal(`{"a":2,"b":true,"c":null}`))
+	})
+
+	it("normalizes numbers like PHP's json_decode and json_encode", func() {
+		encode := func(number string) string {
+			buf := bytes.Buffer{}
+			encodePHP(&buf, json.Number(number))
+			return buf.String()
+		}
+
+		Expect(encode("42")).To(Equal("42"))
+		Expect(encode("-0")).To(Equal("0"))
+		Expect(encode("1.50")).To(Equal("1.5"))
+		Expect(encode("1e2")).To(Equal("100"))
+		Expect(encode("100.0")).To(Equal("100"))
+		Expect(encode("-0.0")).To(Equal("-0"))
+		Expect(encode("0.1")).To(Equal("0.1"))
+		Expect(encode("0.0001")).To(Equal("0.0001"))
+		Expect(encode("0.00001")).To(Equal("1.0e-5"))
+		Expect(encode("1.5E-7")).To(Equal("1.5e-7"))
+		Expect(encode("123456789012345678.0")).To(Equal("1.2345678901234568e+17"))
+		Expect(encode("1e25")).To(Equal("1.0e+25"))
+		Expect(encode("9223372036854775807")).To(Equal("9223372036854775807"))
+		Expect(encode("9223372036854775808")).To(Equal("9.223372036854776e+18"))
+	})
+
+	it("hashes the normalized numbers", func() {
+		hash, err := ContentHash([]byte(`{"require": {"php": ">=7.2"}, "extra": {"ratio": 1.50, "limit": 1e2}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		sum := md5.Sum([]byte(`{"extra":{"ratio":1.5,"limit":100},"require":{"php":">=7.2"}}`))
+		Expect(hash).To(Equal(hex.EncodeToString(sum[:])))
+	})
+
+	it("returns an error for invalid JSON", func() {
+		_, err := ContentHash([]byte(`{"require": `))
+		Expect(err).To(HaveOccurred())
+
+		_, err = ContentHash([]byte(`["not", "an", "object"]`))
+		Expect(err).To(MatchError("composer.json must contain an object"))
+	})
+}
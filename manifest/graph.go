@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"sort"
+	"strings"
+)
+
+// DependencyPaths returns, for each package, the shortest chain of requirements through which composer.json pulls it
+// in, e.g. ["symfony/console", "symfony/polyfill-mbstring"]. Requirements on names a package replaces or provides
+// lead to that package. Paths are keyed by the lowercase package name, as Composer compares names case-insensitively.
+func DependencyPaths(root Manifest, packages []Package, dev bool) map[string][]string {
+	resolvers := map[string][]Package{}
+	for _, pkg := range packages {
+		name := strings.ToLower(pkg.Name)
+		resolvers[name] = append(resolvers[name], pkg)
+		for _, links := range []Links{pkg.Replace, pkg.Provide} {
+			for virtual := range links {
+				virtual = strings.ToLower(virtual)
+				resolvers[virtual] = append(resolvers[virtual], pkg)
+			}
+		}
+	}
+
+	paths := map[string][]string{}
+	var queue []string
+
+	visit := func(parent []string, requirements Links) {
+		for _, requirement := range sortedNames(requirements) {
+			for _, pkg := range resolvers[strings.ToLower(requirement)] {
+				name := strings.ToLower(pkg.Name)
+				if _, ok := paths[name]; ok {
+					continue
+				}
+
+				paths[name] = append(append([]string{}, parent...), pkg.Name)
+				queue = append(queue, name)
+			}
+		}
+	}
+
+	visit(nil, root.Require)
+	if dev {
+		visit(nil, root.RequireDev)
+	}
+
+	// breadth first, so each package is reached through the shortest chain
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, pkg := range resolvers[name] {
+			if strings.EqualFold(pkg.Name, name) {
+				visit(paths[name], pkg.Require)
+			}
+		}
+	}
+
+	return paths
+}
+
+func sortedNames(links Links) []string {
+	var names []string
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitGraph(t *testing.T) {
+	spec.Run(t, "Graph", testGraph, spec.Report(report.Terminal{}))
+}
+
+func testGraph(t *testing.T, when spec.G, it spec.S) {
+	var (
+		root     Manifest
+		packages []Package
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+
+		root = Manifest{
+			Require:    Links{"php": ">=7.2", "symfony/console": "^5.0", "monolog/monolog": "^2.0"},
+			RequireDev: Links{"phpunit/phpunit": "^9.0"},
+		}
+		packages = []Package{
+			{Name: "symfony/console", Require: Links{"symfony/polyfill-mbstring": "^1.8", "psr/log-implementation": "1.0"}},
+			{Name: "symfony/polyfill-mbstring", Require: Links{"php": ">=7.1"}},
+			{Name: "monolog/monolog", Require: Links{"psr/log": "^1.0"}, Provide: Links{"psr/log-implementation": "1.0.0"}},
+			{Name: "Psr/Log"},
+			{Name: "phpunit/phpunit", Require: Links{"sebastian/diff": "^4.0"}},
+			{Name: "sebastian/diff"},
+		}
+	})
+
+	it("finds the shortest requirement chain of each package", func() {
+		paths := DependencyPaths(root, packages, false)
+
+		Expect(paths).To(Equal(map[string][]string{
+			"symfony/console":           {"symfony/console"},
+			"symfony/polyfill-mbstring": {"symfony/console", "symfony/polyfill-mbstring"},
+			"monolog/monolog":           {"monolog/monolog"},
+			"psr/log":                   {"monolog/monolog", "Psr/Log"},
+		}))
+	})
+
+	it("follows dev requirements when dev packages are installed", func() {
+		paths := DependencyPaths(root, packages, true)
+
+		Expect(paths).To(HaveKeyWithValue("sebastian/diff", []string{"phpunit/phpunit", "sebastian/diff"}))
+	})
+}
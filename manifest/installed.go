@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Installed is the parsed content of vendor/composer/installed.json, which Composer writes on every install
+type Installed struct {
+	Packages        []Package `json:"packages"`
+	Dev             bool      `json:"dev"`
+	DevPackageNames []string  `json:"dev-package-names"`
+}
+
+// ParseInstalled parses the content of an installed.json file. Composer 1 writes a list of packages, while Composer 2
+// wraps the list in an object.
+func ParseInstalled(data []byte) (Installed, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		installed := Installed{}
+		if err := json.Unmarshal(trimmed, &installed.Packages); err != nil {
+			return Installed{}, err
+		}
+		return installed, nil
+	}
+
+	installed := Installed{}
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return Installed{}, err
+	}
+
+	return installed, nil
+}
+
+// ReadInstalled reads and parses an installed.json file
+func ReadInstalled(path string) (Installed, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Installed{}, err
+	}
+
+	installed, err := ParseInstalled(buf)
+	if err != nil {
+		return Installed{}, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	return installed, nil
+}
@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitInstalled(t *testing.T) {
+	spec.Run(t, "Installed", testInstalled, spec.Report(report.Terminal{}))
+}
+
+func testInstalled(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("parses the Composer 2 format", func() {
+		installed, err := ParseInstalled([]byte(`{
+	"packages": [{"name": "monolog/monolog", "version": "2.0.1", "license": ["MIT"]}],
+	"dev": true,
+	"dev-package-names": ["phpunit/phpunit"]
+}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed.Packages).To(HaveLen(1))
+		Expect(installed.Packages[0].Name).To(Equal("monolog/monolog"))
+		Expect(installed.Dev).To(BeTrue())
+		Expect(installed.DevPackageNames).To(ConsistOf("phpunit/phpunit"))
+	})
+
+	it("parses the Composer 1 format", func() {
+		installed, err := ParseInstalled([]byte(` [{"name": "psr/log", "version": "1.1.2"}]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(installed.Packages).To(HaveLen(1))
+		Expect(installed.Packages[0].Version).To(Equal("1.1.2"))
+	})
+
+	it("returns an error for invalid content", func() {
+		_, err := ParseInstalled([]byte(`{"packages": {}}`))
+		Expect(err).To(HaveOccurred())
+	})
+}
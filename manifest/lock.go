@@ -0,0 +1,73 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Lock is the parsed content of a composer.lock file
+type Lock struct {
+	ContentHash       string    `json:"content-hash"`
+	Packages          []Package `json:"packages"`
+	PackagesDev       []Package `json:"packages-dev"`
+	MinimumStability  string    `json:"minimum-stability"`
+	PreferStable      bool      `json:"prefer-stable"`
+	Platform          Links     `json:"platform"`
+	PlatformDev       Links     `json:"platform-dev"`
+	PlatformOverrides Links     `json:"platform-overrides"`
+}
+
+// Package is a package locked in composer.lock
+type Package struct {
+	Name        string     `json:"name"`
+	Version     string     `json:"version"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	License     StringList `json:"license"`
+	Source      *Reference `json:"source"`
+	Dist        *Reference `json:"dist"`
+	Require     Links      `json:"require"`
+	RequireDev  Links      `json:"require-dev"`
+	Replace     Links      `json:"replace"`
+	Provide     Links      `json:"provide"`
+	Autoload    Autoload   `json:"autoload"`
+}
+
+// Reference is the "source" or "dist" location of a locked package
+type Reference struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Reference string `json:"reference"`
+	Shasum    string `json:"shasum"`
+}
+
+// ParseLock parses the content of a composer.lock file
+func ParseLock(data []byte) (Lock, error) {
+	lock := Lock{}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return Lock{}, err
+	}
+
+	return lock, nil
+}
+
+// ReadLock reads and parses a composer.lock file
+func ReadLock(path string) (Lock, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Lock{}, err
+	}
+
+	lock, err := ParseLock(buf)
+	if err != nil {
+		return Lock{}, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	return lock, nil
+}
+
+// AllPackages returns the locked packages, followed by the locked dev packages
+func (l Lock) AllPackages() []Package {
+	return append(append([]Package{}, l.Packages...), l.PackagesDev...)
+}
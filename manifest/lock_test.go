@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitLock(t *testing.T) {
+	spec.Run(t, "Lock", testLock, spec.Report(report.Terminal{}))
+}
+
+func testLock(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("parsing composer.lock", func() {
+		it("parses packages and platform", func() {
+			lock, err := ParseLock([]byte(`{
+	"content-hash": "5b7e3d3f4a5b6c7d8e9f0a1b2c3d4e5f",
+	"packages": [{
+		"name": "monolog/monolog",
+		"version": "1.25.1",
+		"source": {"type": "git", "url": "https://github.com/Seldaek/monolog.git", "reference": "70e65a5"},
+		"dist": {"type": "zip", "url": "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5", "reference": "70e65a5", "shasum": ""},
+		"require": {"php": ">=5.3.0", "psr/log": "~1.0"},
+		"require-dev": {"phpunit/phpunit": "~4.5"},
+		"license": ["MIT"],
+		"type": "library"
+	}],
+	"packages-dev": [{"name": "phpunit/phpunit", "version": "8.5.0", "require": []}],
+	"platform": {"php": ">=7.1", "ext-mbstring": "*"},
+	"platform-dev": [],
+	"platform-overrides": {"php": "7.1.3"}
+}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.ContentHash).To(Equal("5b7e3d3f4a5b6c7d8e9f0a1b2c3d4e5f"))
+			Expect(lock.Packages).To(HaveLen(1))
+			Expect(lock.Packages[0].Name).To(Equal("monolog/monolog"))
+			Expect(lock.Packages[0].Version).To(Equal("1.25.1"))
+			Expect(lock.Packages[0].Source.Reference).To(Equal("70e65a5"))
+			Expect(lock.Packages[0].Dist.URL).To(Equal("https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5"))
+			Expect(lock.Packages[0].Require).To(Equal(Links{"php": ">=5.3.0", "psr/log": "~1.0"}))
+			Expect(lock.Packages[0].License).To(Equal(StringList{"MIT"}))
+			Expect(lock.PackagesDev[0].Require).To(BeEmpty())
+			Expect(lock.Platform).To(Equal(Links{"php": ">=7.1", "ext-mbstring": "*"}))
+			Expect(lock.PlatformDev).To(BeEmpty())
+			Expect(lock.PlatformOverrides).To(Equal(Links{"php": "7.1.3"}))
+			Expect(lock.AllPackages()).To(HaveLen(2))
+		})
+
+		it("accepts platform written as an empty array", func() {
+			lock, err := ParseLock([]byte(`{"platform": []}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Platform).To(BeEmpty())
+		})
+
+		it("still fails on platform written as a non-empty array", func() {
+			_, err := ParseLock([]byte(`{"platform": ["php"]}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Manifest is the parsed content of a composer.json file
+type Manifest struct {
+	Name             string       `json:"name"`
+	Type             string       `json:"type"`
+	License          StringList   `json:"license"`
+	MinimumStability string       `json:"minimum-stability"`
+	Require          Links        `json:"require"`
+	RequireDev       Links        `json:"require-dev"`
+	Config           Config       `json:"config"`
+	Scripts          Scripts      `json:"scripts"`
+	Autoload         Autoload     `json:"autoload"`
+	AutoloadDev      Autoload     `json:"autoload-dev"`
+	Repositories     Repositories `json:"repositories"`
+}
+
+// Config is the "config" section of composer.json
+type Config struct {
+	VendorDir             string `json:"vendor-dir"`
+	BinDir                string `json:"bin-dir"`
+	Platform              Links  `json:"platform"`
+	OptimizeAutoloader    bool   `json:"optimize-autoloader"`
+	ClassmapAuthoritative bool   `json:"classmap-authoritative"`
+	APCuAutoloader        bool   `json:"apcu-autoloader"`
+}
+
+// Autoload is the "autoload" and "autoload-dev" section of composer.json and of locked packages
+type Autoload struct {
+	PSR4                Namespaces `json:"psr-4"`
+	PSR0                Namespaces `json:"psr-0"`
+	Classmap            StringList `json:"classmap"`
+	Files               StringList `json:"files"`
+	ExcludeFromClassmap StringList `json:"exclude-from-classmap"`
+}
+
+// Scripts maps Composer event names to the commands run for them
+type Scripts map[string]StringList
+
+// Namespaces maps autoloaded namespaces to their directories
+type Namespaces map[string]StringList
+
+// Links maps package names to version constraints. A constraint of `false`, which Composer uses in config.platform to
+// hide a platform package, is read as an empty constraint.
+type Links map[string]string
+
+// StringList is a list of strings that may also be written as a single string
+type StringList []string
+
+// ParseManifest parses the content of a composer.json file
+func ParseManifest(data []byte) (Manifest, error) {
+	manifest := Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// ReadManifest reads and parses a composer.json file
+func ReadManifest(path string) (Manifest, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest, err := ParseManifest(buf)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+func (l *Links) UnmarshalJSON(data []byte) error {
+	raw := map[string]interface{}{}
+	if err := unmarshalObject(data, &raw); err != nil {
+		return err
+	}
+
+	links := Links{}
+	for name, value := range raw {
+		switch constraint := value.(type) {
+		case string:
+			links[name] = constraint
+		case bool:
+			if constraint {
+				return fmt.Errorf("invalid constraint for %s: true", name)
+			}
+			links[name] = ""
+		default:
+			return fmt.Errorf("invalid constraint for %s: %v", name, value)
+		}
+	}
+
+	*l = links
+	return nil
+}
+
+func (s *Scripts) UnmarshalJSON(data []byte) error {
+	scripts := map[string]StringList{}
+	if err := unmarshalObject(data, &scripts); err != nil {
+		return err
+	}
+
+	*s = scripts
+	return nil
+}
+
+func (n *Namespaces) UnmarshalJSON(data []byte) error {
+	namespaces := map[string]StringList{}
+	if err := unmarshalObject(data, &namespaces); err != nil {
+		return err
+	}
+
+	*n = namespaces
+	return nil
+}
+
+func (s *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*s = list
+	return nil
+}
+
+// unmarshalObject decodes a JSON object, accepting `[]` as an empty object because that is how PHP encodes empty maps
+func unmarshalObject(data []byte, v interface{}) error {
+	if isEmptyArray(data) {
+		return nil
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func isEmptyArray(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) < 2 || trimmed[0] != '[' || trimmed[len(trimmed)-1] != ']' {
+		return false
+	}
+
+	return len(bytes.TrimSpace(trimmed[1:len(trimmed)-1])) == 0
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitManifest(t *testing.T) {
+	spec.Run(t, "Manifest", testManifest, spec.Report(report.Terminal{}))
+}
+
+func testManifest(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("parsing composer.json", func() {
+		it("parses every section", func() {
+			m, err := ParseManifest([]byte(`{
+	"name": "acme/app",
+	"license": "MIT",
+	"require": {"php": ">=7.2", "ext-mbstring": "*", "monolog/monolog": "^1.0"},
+	"require-dev": {"phpunit/phpunit": "^8"},
+	"config": {"vendor-dir": "lib/vendor", "optimize-autoloader": true, "platform": {"php": "7.2.5", "ext-gd": false}},
+	"scripts": {"post-install-cmd": "@php artisan optimize", "test": ["phpunit", "phpcs"]},
+	"autoload": {"psr-4": {"App\\": "src/", "Lib\\": ["lib/", "vendor-lib/"]}, "classmap": ["database/"], "files": ["helpers.php"]},
+	"repositories": [{"type": "path", "url": "../lib"}, {"packagist.org": false}]
+}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(m.Name).To(Equal("acme/app"))
+			Expect(m.License).To(Equal(StringList{"MIT"}))
+			Expect(m.Require).To(Equal(Links{"php": ">=7.2", "ext-mbstring": "*", "monolog/monolog": "^1.0"}))
+			Expect(m.RequireDev).To(Equal(Links{"phpunit/phpunit": "^8"}))
+			Expect(m.Config.VendorDir).To(Equal("lib/vendor"))
+			Expect(m.Config.OptimizeAutoloader).To(BeTrue())
+			Expect(m.Config.Platform).To(Equal(Links{"php": "7.2.5", "ext-gd": ""}))
+			Expect(m.Scripts).To(Equal(Scripts{
+				"post-install-cmd": {"@php artisan optimize"},
+				"test":             {"phpunit", "phpcs"},
+			}))
+			Expect(m.Autoload.PSR4).To(Equal(Namespaces{"App\\": {"src/"}, "Lib\\": {"lib/", "vendor-lib/"}}))
+			Expect(m.Autoload.Classmap).To(Equal(StringList{"database/"}))
+			Expect(m.Autoload.Files).To(Equal(StringList{"helpers.php"}))
+			Expect(m.Repositories).To(Equal(Repositories{
+				{Type: "path", URL: "../lib"},
+				{Name: "packagist.org", Disabled: true},
+			}))
+			Expect(m.Repositories.OfType("path")).To(HaveLen(1))
+		})
+
+		it("accepts the array form of empty objects", func() {
+			m, err := ParseManifest([]byte(`{"require": [], "scripts": [], "autoload": {"psr-4": []}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Require).To(BeEmpty())
+			Expect(m.Scripts).To(BeEmpty())
+			Expect(m.Autoload.PSR4).To(BeEmpty())
+		})
+
+		it("keeps the order of repositories written as an object", func() {
+			m, err := ParseManifest([]byte(`{"repositories": {"satis": {"type": "composer", "url": "https://satis.example.com"}, "packagist.org": false, "local": {"type": "path", "url": "packages/*", "options": {"symlink": false}}}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Repositories).To(Equal(Repositories{
+				{Name: "satis", Type: "composer", URL: "https://satis.example.com"},
+				{Name: "packagist.org", Disabled: true},
+				{Name: "local", Type: "path", URL: "packages/*", Options: map[string]interface{}{"symlink": false}},
+			}))
+		})
+
+		it("treats null repositories as no repositories", func() {
+			m, err := ParseManifest([]byte(`{"repositories": null}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Repositories).To(BeEmpty())
+		})
+
+		it("returns an error for invalid constraints", func() {
+			_, err := ParseManifest([]byte(`{"require": {"php": 7}}`))
+			Expect(err).To(MatchError(ContainSubstring("invalid constraint for php")))
+		})
+	})
+
+	when("reading composer.json from disk", func() {
+		it("includes the path in parse errors", func() {
+			path := filepath.Join(test.ScratchDir(t, "manifest"), "composer.json")
+			test.WriteFile(t, path, "{not json")
+
+			_, err := ReadManifest(path)
+			Expect(err).To(MatchError(ContainSubstring(path)))
+		})
+	})
+}
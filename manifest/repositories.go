@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Repository is an entry of the "repositories" section of composer.json
+type Repository struct {
+	// Name is the key of the repository when "repositories" is written as an object
+	Name     string                 `json:"-"`
+	Type     string                 `json:"type"`
+	URL      string                 `json:"url"`
+	Options  map[string]interface{} `json:"options"`
+	Disabled bool                   `json:"-"`
+}
+
+// Repositories keeps the order of the "repositories" section, which Composer uses as the lookup priority. Both the
+// list and the object form are accepted, and entries such as `{"packagist.org": false}` are read as disabled
+// repositories.
+type Repositories []Repository
+
+// OfType returns the repositories with the given type
+func (r Repositories) OfType(repositoryType string) Repositories {
+	var matches Repositories
+	for _, repository := range r {
+		if !repository.Disabled && repository.Type == repositoryType {
+			matches = append(matches, repository)
+		}
+	}
+
+	return matches
+}
+
+func (r *Repositories) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		*r = nil
+		return nil
+	}
+
+	var repositories Repositories
+	switch trimmed[0] {
+	case '[':
+		var entries []json.RawMessage
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			repository, err := parseRepository("", entry)
+			if err != nil {
+				return err
+			}
+			repositories = append(repositories, repository...)
+		}
+	case '{':
+		err := eachObjectMember(trimmed, func(name string, value json.RawMessage) error {
+			repository, err := parseRepository(name, value)
+			if err != nil {
+				return err
+			}
+			repositories = append(repositories, repository...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("repositories must be a list or an object")
+	}
+
+	*r = repositories
+	return nil
+}
+
+func parseRepository(name string, data json.RawMessage) (Repositories, error) {
+	var disabled bool
+	if err := json.Unmarshal(data, &disabled); err == nil {
+		if disabled {
+			return nil, fmt.Errorf("invalid repository %s: true", name)
+		}
+		return Repositories{{Name: name, Disabled: true}}, nil
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	// a list entry without a type disables repositories by name, e.g. [{"packagist.org": false}]
+	if _, ok := raw["type"]; !ok && name == "" {
+		var repositories Repositories
+		err := eachObjectMember(data, func(name string, value json.RawMessage) error {
+			repository, err := parseRepository(name, value)
+			if err != nil {
+				return err
+			}
+			repositories = append(repositories, repository...)
+			return nil
+		})
+		return repositories, err
+	}
+
+	repository := Repository{Name: name}
+	if err := json.Unmarshal(data, &repository); err != nil {
+		return nil, err
+	}
+
+	return Repositories{repository}, nil
+}
+
+// eachObjectMember calls fn for each member of a JSON object, in document order
+func eachObjectMember(data []byte, fn func(name string, value json.RawMessage) error) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		if err := fn(token.(string), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
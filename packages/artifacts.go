@@ -0,0 +1,299 @@
+package packages
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/bindings"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// ArtifactBindingTypes are the binding types whose directory is an artifact repository of package dists
+var ArtifactBindingTypes = []string{"composer-artifacts"}
+
+// artifact is a package dist in an artifact repository
+type artifact struct {
+	name    string
+	version string
+	path    string
+}
+
+// artifactRepositories returns the directories of the artifact repositories from BP_COMPOSER_ARTIFACTS and bindings
+func (c Contributor) artifactRepositories() ([]string, error) {
+	var dirs []string
+
+	if dir := c.composerConfig.Artifacts; dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(c.app.Root, dir)
+		}
+
+		if exists, err := helper.FileExists(dir); err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, fmt.Errorf("artifact repository %s does not exist", dir)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	all, err := bindings.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, binding := range bindings.OfType(all, ArtifactBindingTypes...) {
+		dirs = append(dirs, binding.Path)
+	}
+
+	return dirs, nil
+}
+
+// useArtifacts points every locked package at its dist in the artifact repositories and disables network access, so
+// that Composer installs exclusively from them. It fails when a locked package is missing from the repositories.
+// Packages from path repositories and metapackages have no dist to install and keep their entry. The rewritten
+// composer.lock is written next to a copy of composer.json outside of the app, which COMPOSER points at. The returned
+// function removes them and restores network access.
+func (c Contributor) useArtifacts() (func() error, error) {
+	restore := func() error { return nil }
+
+	dirs, err := c.artifactRepositories()
+	if err != nil || len(dirs) == 0 {
+		return restore, err
+	}
+
+	lockPath := filepath.Join(filepath.Dir(c.composerJSONPath), composer.ComposerLock)
+	if exists, err := helper.FileExists(lockPath); err != nil {
+		return restore, err
+	} else if !exists {
+		return restore, fmt.Errorf("installing from an artifact repository requires a %s", composer.ComposerLock)
+	}
+
+	original, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return restore, err
+	}
+
+	lock, err := manifest.ParseLock(original)
+	if err != nil {
+		return restore, err
+	}
+
+	artifacts := map[string]artifact{}
+	for _, dir := range dirs {
+		found, err := readArtifacts(dir)
+		if err != nil {
+			return restore, err
+		}
+		for _, a := range found {
+			key := artifactKey(a.name, a.version)
+			if _, ok := artifacts[key]; !ok {
+				artifacts[key] = a
+			}
+		}
+	}
+
+	packages := lock.Packages
+	if !composer.Contains(c.composerConfig.InstallOptions, "--no-dev") {
+		packages = lock.AllPackages()
+	}
+
+	var installed int
+	var missing []string
+	for _, pkg := range packages {
+		if !hasArtifactDist(pkg.Dist) {
+			continue
+		}
+		installed++
+		if _, ok := artifacts[artifactKey(pkg.Name, pkg.Version)]; !ok {
+			missing = append(missing, fmt.Sprintf("  %s %s", pkg.Name, pkg.Version))
+		}
+	}
+	if len(missing) > 0 {
+		return restore, fmt.Errorf("%d locked packages are missing from the artifact repository %s:\n%s", len(missing), strings.Join(dirs, ", "), strings.Join(missing, "\n"))
+	}
+
+	rewritten, err := rewriteLockDists(original, artifacts)
+	if err != nil {
+		return restore, fmt.Errorf("unable to rewrite %s: %w", lockPath, err)
+	}
+
+	dir, err := ioutil.TempDir("", "composer-artifacts")
+	if err != nil {
+		return restore, err
+	}
+	cleanup := func() error {
+		if err := os.Unsetenv("COMPOSER_DISABLE_NETWORK"); err != nil {
+			return err
+		}
+		if err := os.Unsetenv("COMPOSER"); err != nil {
+			return err
+		}
+		return os.RemoveAll(dir)
+	}
+
+	if err := useLock(dir, c.composerJSONPath, rewritten); err != nil {
+		_ = cleanup()
+		return restore, err
+	}
+
+	c.composer.Logger.Body("Installing %d packages from the artifact repository %s", installed, strings.Join(dirs, ", "))
+
+	return cleanup, nil
+}
+
+// useLock writes composer.json with the lock next to it to dir and points Composer and network access at them
+func useLock(dir, composerJSONPath string, lock []byte) error {
+	if err := helper.CopyFile(composerJSONPath, filepath.Join(dir, composer.ComposerJSON)); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, composer.ComposerLock), lock, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Setenv("COMPOSER", filepath.Join(dir, composer.ComposerJSON)); err != nil {
+		return err
+	}
+
+	return os.Setenv("COMPOSER_DISABLE_NETWORK", "1")
+}
+
+// hasArtifactDist reports whether a locked package is installed from a dist that an artifact replaces, which excludes
+// metapackages and packages from path repositories
+func hasArtifactDist(dist *manifest.Reference) bool {
+	return dist != nil && dist.Type != "path"
+}
+
+// readArtifacts reads the name and version of every zip dist in an artifact repository, as Composer's artifact
+// repository does
+func readArtifacts(dir string) ([]artifact, error) {
+	var artifacts []artifact
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".zip") {
+			return nil
+		}
+
+		a, err := readArtifact(path)
+		if err != nil {
+			return fmt.Errorf("unable to read artifact %s: %w", path, err)
+		}
+		artifacts = append(artifacts, a)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].path < artifacts[j].path })
+	return artifacts, nil
+}
+
+// readArtifact reads the composer.json closest to the root of a zip dist
+func readArtifact(path string) (artifact, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return artifact{}, err
+	}
+	defer archive.Close()
+
+	var composerJSON *zip.File
+	for _, file := range archive.File {
+		if filepath.Base(file.Name) != composer.ComposerJSON {
+			continue
+		}
+		if composerJSON == nil || strings.Count(file.Name, "/") < strings.Count(composerJSON.Name, "/") {
+			composerJSON = file
+		}
+	}
+	if composerJSON == nil {
+		return artifact{}, fmt.Errorf("no %s found", composer.ComposerJSON)
+	}
+
+	reader, err := composerJSON.Open()
+	if err != nil {
+		return artifact{}, err
+	}
+	defer reader.Close()
+
+	var metadata struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(reader).Decode(&metadata); err != nil {
+		return artifact{}, err
+	}
+	if metadata.Name == "" || metadata.Version == "" {
+		return artifact{}, fmt.Errorf("%s must declare a name and a version", composerJSON.Name)
+	}
+
+	return artifact{name: metadata.Name, version: metadata.Version, path: path}, nil
+}
+
+// rewriteLockDists replaces the dist of every locked package with its artifact and drops its source, keeping
+// everything else in composer.lock as it is
+func rewriteLockDists(lock []byte, artifacts map[string]artifact) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(lock))
+	decoder.UseNumber()
+
+	var content map[string]interface{}
+	if err := decoder.Decode(&content); err != nil {
+		return nil, err
+	}
+
+	for _, section := range []string{"packages", "packages-dev"} {
+		packages, _ := content[section].([]interface{})
+		for _, entry := range packages {
+			pkg, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			previous, ok := pkg["dist"].(map[string]interface{})
+			if !ok || previous["type"] == "path" {
+				continue
+			}
+
+			name, _ := pkg["name"].(string)
+			version, _ := pkg["version"].(string)
+			a, ok := artifacts[artifactKey(name, version)]
+			if !ok {
+				continue
+			}
+
+			dist := map[string]interface{}{"type": "zip", "url": a.path, "shasum": ""}
+			if previous["reference"] != nil {
+				dist["reference"] = previous["reference"]
+			}
+			pkg["dist"] = dist
+			delete(pkg, "source")
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(content); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// artifactKey identifies a package version, ignoring the case of the name and a v prefix of the version
+func artifactKey(name, version string) string {
+	return strings.ToLower(name) + "@" + strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+}
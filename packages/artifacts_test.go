@@ -0,0 +1,172 @@
+package packages
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitArtifacts(t *testing.T) {
+	spec.Run(t, "Artifacts", testArtifacts, spec.Report(report.Terminal{}))
+}
+
+func testArtifacts(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory  *test.BuildFactory
+		info     *bytes.Buffer
+		root     string
+		lockPath string
+		lock     string
+	)
+
+	writeArtifact := func(path, name, composerJSON string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		file, err := os.Create(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		archive := zip.NewWriter(file)
+		entry, err := archive.Create(name)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = entry.Write([]byte(composerJSON))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archive.Close()).To(Succeed())
+	}
+
+	newContributor := func() Contributor {
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+		return contributor
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+
+		lockPath = filepath.Join(factory.Build.Application.Root, composer.ComposerLock)
+		lock = `{
+    "content-hash": "abc",
+    "packages": [
+        {"name": "monolog/monolog", "version": "1.25.1", "source": {"type": "git", "url": "https://github.com/Seldaek/monolog.git", "reference": "70e65a5"}, "dist": {"type": "zip", "url": "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5", "reference": "70e65a5", "shasum": ""}},
+        {"name": "psr/log", "version": "1.1.2", "dist": {"type": "zip", "url": "https://api.github.com/repos/php-fig/log/zipball/446d54b", "reference": "446d54b", "shasum": ""}},
+        {"name": "acme/shared", "version": "dev-main", "dist": {"type": "path", "url": "../shared", "reference": "abc"}},
+        {"name": "acme/bundle", "version": "1.0.0"}
+    ],
+    "packages-dev": [{"name": "phpunit/phpunit", "version": "8.5.0", "dist": {"type": "zip", "url": "https://api.github.com/repos/sebastianbergmann/phpunit/zipball/3ee1c1f", "reference": "3ee1c1f", "shasum": ""}}],
+    "plugin-api-version": "2.0.0"
+}`
+		test.WriteFile(t, lockPath, lock)
+
+		writeArtifact(filepath.Join(factory.Build.Application.Root, "artifacts", "monolog.zip"), "monolog-70e65a5/composer.json", `{"name": "monolog/monolog", "version": "v1.25.1"}`)
+		writeArtifact(filepath.Join(factory.Build.Application.Root, "artifacts", "psr", "log.zip"), "composer.json", `{"name": "psr/log", "version": "1.1.2"}`)
+
+		root = test.ScratchDir(t, "bindings")
+		Expect(os.Setenv("SERVICE_BINDING_ROOT", root)).To(Succeed())
+		info = &bytes.Buffer{}
+	})
+
+	it.After(func() {
+		for _, env := range []string{"SERVICE_BINDING_ROOT", "COMPOSER", "COMPOSER_DISABLE_NETWORK", composer.ArtifactsEnv} {
+			Expect(os.Unsetenv(env)).To(Succeed())
+		}
+	})
+
+	it("does nothing without an artifact repository", func() {
+		restore, err := newContributor().useArtifacts()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restore()).To(Succeed())
+
+		contents, err := ioutil.ReadFile(lockPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(lock))
+	})
+
+	it("installs the locked packages from the artifacts until restored", func() {
+		Expect(os.Setenv(composer.ArtifactsEnv, "artifacts")).To(Succeed())
+
+		restore, err := newContributor().useArtifacts()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Getenv("COMPOSER_DISABLE_NETWORK")).To(Equal("1"))
+		Expect(info.String()).To(ContainSubstring("Installing 2 packages from the artifact repository"))
+
+		Expect(os.Getenv("COMPOSER")).NotTo(HavePrefix(factory.Build.Application.Root))
+		Expect(filepath.Join(filepath.Dir(os.Getenv("COMPOSER")), composer.ComposerJSON)).To(BeARegularFile())
+		rewritten, err := manifest.ReadLock(filepath.Join(filepath.Dir(os.Getenv("COMPOSER")), composer.ComposerLock))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rewritten.ContentHash).To(Equal("abc"))
+		Expect(rewritten.Packages[0].Source).To(BeNil())
+		Expect(*rewritten.Packages[0].Dist).To(Equal(manifest.Reference{
+			Type:      "zip",
+			URL:       filepath.Join(factory.Build.Application.Root, "artifacts", "monolog.zip"),
+			Reference: "70e65a5",
+		}))
+		Expect(rewritten.Packages[1].Dist.URL).To(Equal(filepath.Join(factory.Build.Application.Root, "artifacts", "psr", "log.zip")))
+		Expect(*rewritten.Packages[2].Dist).To(Equal(manifest.Reference{Type: "path", URL: "../shared", Reference: "abc"}))
+		Expect(rewritten.Packages[3].Dist).To(BeNil())
+
+		dir := filepath.Dir(os.Getenv("COMPOSER"))
+		Expect(restore()).To(Succeed())
+		Expect(os.Getenv("COMPOSER_DISABLE_NETWORK")).To(BeEmpty())
+		Expect(os.Getenv("COMPOSER")).To(BeEmpty())
+		Expect(dir).NotTo(BeADirectory())
+		contents, err := ioutil.ReadFile(lockPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(lock))
+	})
+
+	it("reads artifact repositories from bindings", func() {
+		test.WriteFile(t, filepath.Join(root, "dists", "type"), "composer-artifacts")
+		writeArtifact(filepath.Join(root, "dists", "monolog.zip"), "composer.json", `{"name": "monolog/monolog", "version": "1.25.1"}`)
+		writeArtifact(filepath.Join(root, "dists", "log.zip"), "composer.json", `{"name": "psr/log", "version": "1.1.2"}`)
+
+		restore, err := newContributor().useArtifacts()
+		Expect(err).NotTo(HaveOccurred())
+		defer restore()
+
+		rewritten, err := manifest.ReadLock(filepath.Join(filepath.Dir(os.Getenv("COMPOSER")), composer.ComposerLock))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rewritten.Packages[0].Dist.URL).To(Equal(filepath.Join(root, "dists", "monolog.zip")))
+	})
+
+	it("fails when a locked package is missing", func() {
+		Expect(os.Setenv(composer.ArtifactsEnv, "artifacts")).To(Succeed())
+		Expect(os.Setenv(composer.InstallOptionsEnv, "")).To(Succeed())
+		defer os.Unsetenv(composer.InstallOptionsEnv)
+
+		_, err := newContributor().useArtifacts()
+		Expect(err).To(MatchError(ContainSubstring("1 locked packages are missing from the artifact repository")))
+		Expect(err).To(MatchError(ContainSubstring("  phpunit/phpunit 8.5.0")))
+		Expect(os.Getenv("COMPOSER_DISABLE_NETWORK")).To(BeEmpty())
+	})
+
+	it("requires a lock file", func() {
+		Expect(os.Setenv(composer.ArtifactsEnv, "artifacts")).To(Succeed())
+		Expect(os.Remove(lockPath)).To(Succeed())
+
+		_, err := newContributor().useArtifacts()
+		Expect(err).To(MatchError("installing from an artifact repository requires a composer.lock"))
+	})
+
+	it("rejects artifacts without a version", func() {
+		Expect(os.Setenv(composer.ArtifactsEnv, "artifacts")).To(Succeed())
+		writeArtifact(filepath.Join(factory.Build.Application.Root, "artifacts", "broken.zip"), "composer.json", `{"name": "acme/broken"}`)
+
+		_, err := newContributor().useArtifacts()
+		Expect(err).To(MatchError(ContainSubstring("broken.zip: composer.json must declare a name and a version")))
+	})
+}
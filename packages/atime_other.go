@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package packages
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime falls back to the modification time on platforms without a portable access time
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
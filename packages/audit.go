@@ -0,0 +1,129 @@
+package packages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/cloudfoundry/libcfbuildpack/layers"
+	"github.com/paketo-buildpacks/php-composer/audit"
+	"github.com/paketo-buildpacks/php-composer/bindings"
+)
+
+// AdvisoryBindingTypes are the binding types that provide security advisories for the offline audit
+var AdvisoryBindingTypes = []string{"composer-advisories", "security-advisories"}
+
+// advisories loads the advisory database from BP_COMPOSER_AUDIT_ADVISORIES and from bindings, returning the sources
+// it was loaded from
+func (c Contributor) advisories() (audit.Database, []string, error) {
+	db := audit.Database{}
+	var sources []string
+
+	if path := c.composerConfig.AuditAdvisories; path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.app.Root, path)
+		}
+
+		loaded, err := audit.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		db.Merge(loaded)
+		sources = append(sources, path)
+	}
+
+	all, err := bindings.Resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, binding := range bindings.OfType(all, AdvisoryBindingTypes...) {
+		for _, key := range binding.Keys() {
+			loaded, err := audit.Load(binding.Entries[key])
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to read advisories from binding %s: %w", binding.Name, err)
+			}
+			db.Merge(loaded)
+		}
+		sources = append(sources, "binding "+binding.Name)
+	}
+
+	return db, sources, nil
+}
+
+// auditPackages checks the installed packages against the local advisory database, warns about or fails on the
+// affected packages depending on the severity thresholds and writes the report to the audit layer
+func (c Contributor) auditPackages() error {
+	db, sources, err := c.advisories()
+	if err != nil {
+		return err
+	} else if len(sources) == 0 {
+		return nil
+	}
+
+	logger := c.composer.Logger
+	logger.Header("Security audit")
+
+	packages, source, err := c.installedPackages()
+	if err != nil {
+		return err
+	} else if source == "" {
+		logger.BodyWarning("Unable to audit the installed packages, neither composer.lock nor installed.json was found")
+		return nil
+	}
+
+	report, err := audit.Audit(db, packages)
+	if err != nil {
+		return err
+	}
+	report.Sources = sources
+
+	logger.Body("Checked %d packages against %d advisories from %s", report.Packages, report.Advisories, strings.Join(sources, ", "))
+
+	var failures []string
+	for _, finding := range report.Findings {
+		message := fmt.Sprintf("%s %s is affected by %s: %s (severity %s)", finding.Package, finding.Version, finding.ID, finding.Title, finding.Severity)
+		switch {
+		case audit.Reaches(finding.Severity, c.composerConfig.AuditFail):
+			failures = append(failures, "  "+message)
+			logger.BodyError("%s", message)
+		case audit.Reaches(finding.Severity, c.composerConfig.AuditWarn):
+			logger.BodyWarning("%s", message)
+		default:
+			logger.Body("%s", message)
+		}
+	}
+
+	for _, unchecked := range report.Unchecked {
+		logger.BodyWarning("Unable to compare the version of %s with its advisories", unchecked)
+	}
+
+	if err := c.writeAuditReport(report); err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d advisories at or above the %s severity affect the installed packages:\n%s", len(failures), c.composerConfig.AuditFail, strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+// writeAuditReport stores the report as audit.json in the audit layer, which is replaced whenever the report changes
+func (c Contributor) writeAuditReport(report audit.Report) error {
+	buf, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(buf)
+	metadata := Metadata{Name: "PHP Composer Audit", Hash: hex.EncodeToString(hash[:])}
+
+	return c.auditLayer.Contribute(metadata, func(layer layers.Layer) error {
+		return helper.WriteFile(filepath.Join(layer.Root, "audit.json"), 0644, "%s", buf)
+	}, layers.Launch)
+}
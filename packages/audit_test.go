@@ -0,0 +1,116 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/audit"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitAudit(t *testing.T) {
+	spec.Run(t, "Audit", testAudit, spec.Report(report.Terminal{}))
+}
+
+func testAudit(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory *test.BuildFactory
+		info    *bytes.Buffer
+		root    string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerLock), `{"packages": [
+	{"name": "monolog/monolog", "version": "1.11.0"},
+	{"name": "psr/log", "version": "1.1.2"}
+]}`)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "advisories", "advisories.json"), `{"advisories": {
+	"monolog/monolog": [{"advisoryId": "PKSA-1", "title": "Header injection", "affectedVersions": ">=1.8.0,<1.12.0", "severity": "high"}],
+	"psr/log": [{"advisoryId": "PKSA-2", "title": "Not affected", "affectedVersions": "<1.0.0", "severity": "critical"}]
+}}`)
+
+		root = test.ScratchDir(t, "bindings")
+		Expect(os.Setenv("SERVICE_BINDING_ROOT", root)).To(Succeed())
+		info = &bytes.Buffer{}
+	})
+
+	it.After(func() {
+		for _, env := range []string{"SERVICE_BINDING_ROOT", composer.AuditAdvisoriesEnv, composer.AuditWarnSeverityEnv, composer.AuditFailSeverityEnv} {
+			Expect(os.Unsetenv(env)).To(Succeed())
+		}
+	})
+
+	newContributor := func() Contributor {
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+		return contributor
+	}
+
+	readReport := func(contributor Contributor) audit.Report {
+		buf, err := ioutil.ReadFile(filepath.Join(contributor.auditLayer.Root, "audit.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		report := audit.Report{}
+		Expect(json.Unmarshal(buf, &report)).To(Succeed())
+		return report
+	}
+
+	it("does nothing without advisories", func() {
+		contributor := newContributor()
+		Expect(contributor.auditPackages()).To(Succeed())
+		Expect(info.String()).To(BeEmpty())
+		Expect(filepath.Join(contributor.auditLayer.Root, "audit.json")).NotTo(BeAnExistingFile())
+	})
+
+	it("warns about affected packages and writes the report", func() {
+		Expect(os.Setenv(composer.AuditAdvisoriesEnv, "advisories")).To(Succeed())
+
+		contributor := newContributor()
+		Expect(contributor.auditPackages()).To(Succeed())
+		Expect(info.String()).To(ContainSubstring("Checked 2 packages against 2 advisories"))
+		Expect(info.String()).To(ContainSubstring("monolog/monolog 1.11.0 is affected by PKSA-1: Header injection (severity high)"))
+		Expect(info.String()).NotTo(ContainSubstring("PKSA-2"))
+
+		report := readReport(contributor)
+		Expect(report.Findings).To(HaveLen(1))
+		Expect(report.Findings[0].ID).To(Equal("PKSA-1"))
+		Expect(contributor.auditLayer).To(test.HaveLayerMetadata(false, false, true))
+	})
+
+	it("fails when an advisory reaches the failure threshold", func() {
+		Expect(os.Setenv(composer.AuditAdvisoriesEnv, "advisories")).To(Succeed())
+		Expect(os.Setenv(composer.AuditFailSeverityEnv, "high")).To(Succeed())
+
+		contributor := newContributor()
+		err := contributor.auditPackages()
+		Expect(err).To(MatchError(ContainSubstring("1 advisories at or above the high severity affect the installed packages")))
+		Expect(err).To(MatchError(ContainSubstring("monolog/monolog 1.11.0 is affected by PKSA-1")))
+		Expect(readReport(contributor).Findings).To(HaveLen(1))
+	})
+
+	it("reads advisories from bindings", func() {
+		test.WriteFile(t, filepath.Join(root, "advisories", "type"), "composer-advisories")
+		test.WriteFile(t, filepath.Join(root, "advisories", "monolog.yaml"), "title: Binding advisory\nreference: composer://monolog/monolog\nbranches: {1.x: {versions: ['<1.12.0']}}\n")
+
+		contributor := newContributor()
+		Expect(contributor.auditPackages()).To(Succeed())
+		Expect(info.String()).To(ContainSubstring("from binding advisories"))
+		Expect(info.String()).To(ContainSubstring("Binding advisory (severity unknown)"))
+	})
+}
@@ -0,0 +1,148 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/bindings"
+)
+
+// AuthBindingTypes are the binding types that provide credentials for Composer repositories
+var AuthBindingTypes = []string{"composer", "composer-auth"}
+
+// authEntries map binding entries holding a single token to their auth.json section
+var authEntries = map[string]string{
+	"github-oauth": "github-oauth",
+	"gitlab-token": "gitlab-token",
+	"gitlab-oauth": "gitlab-oauth",
+	"bearer":       "bearer",
+}
+
+// auth is the content of Composer's auth.json: sections such as http-basic, each mapping hosts to credentials
+type auth map[string]map[string]interface{}
+
+func (a auth) add(section, host string, credentials interface{}) {
+	if a[section] == nil {
+		a[section] = map[string]interface{}{}
+	}
+	a[section][host] = credentials
+}
+
+func (c Contributor) authPath() string {
+	return filepath.Join(os.Getenv("COMPOSER_HOME"), "auth.json")
+}
+
+// configureAuth writes the credentials from `composer` and `composer-auth` bindings to COMPOSER_HOME/auth.json.
+// COMPOSER_HOME lives in the composer layer, which is never part of the launch image, and the file is removed again
+// once the packages are installed.
+func (c Contributor) configureAuth() error {
+	all, err := bindings.Resolve()
+	if err != nil {
+		return err
+	}
+
+	authBindings := bindings.OfType(all, AuthBindingTypes...)
+	if len(authBindings) == 0 {
+		return nil
+	}
+
+	credentials := auth{}
+	for _, binding := range authBindings {
+		if err := addBindingAuth(credentials, binding); err != nil {
+			return fmt.Errorf("unable to read credentials from binding %s: %w", binding.Name, err)
+		}
+	}
+
+	var sections []string
+	for section := range credentials {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		var hosts []string
+		for host := range credentials[section] {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+
+		for _, host := range hosts {
+			c.composer.Logger.Body("Configuring %s credentials for %s", section, host)
+		}
+	}
+
+	buf, err := json.MarshalIndent(credentials, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return helper.WriteFile(c.authPath(), 0600, "%s", buf)
+}
+
+func (c Contributor) removeAuth() error {
+	if err := os.Remove(c.authPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// addBindingAuth reads either a complete auth.json entry or a `host` entry with the credentials for that host
+func addBindingAuth(credentials auth, binding bindings.Binding) error {
+	if content, ok, err := binding.Value("auth.json"); err != nil {
+		return err
+	} else if ok {
+		parsed := auth{}
+		if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+			return fmt.Errorf("invalid auth.json: %w", err)
+		}
+
+		for section, hosts := range parsed {
+			for host, value := range hosts {
+				credentials.add(section, host, value)
+			}
+		}
+	}
+
+	host, ok, err := binding.Value("host")
+	if err != nil || !ok {
+		return err
+	}
+
+	values := map[string]string{}
+	for _, key := range binding.Keys() {
+		if values[key], _, err = binding.Value(key); err != nil {
+			return err
+		}
+	}
+
+	found := false
+	if values["username"] != "" || values["password"] != "" {
+		credentials.add("http-basic", host, map[string]string{"username": values["username"], "password": values["password"]})
+		found = true
+	}
+
+	if values["bitbucket-consumer-key"] != "" {
+		credentials.add("bitbucket-oauth", host, map[string]string{
+			"consumer-key":    values["bitbucket-consumer-key"],
+			"consumer-secret": values["bitbucket-consumer-secret"],
+		})
+		found = true
+	}
+
+	for key, section := range authEntries {
+		if values[key] != "" {
+			credentials.add(section, host, values[key])
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no credentials found for host %s", host)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package packages
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitAuth(t *testing.T) {
+	spec.Run(t, "Auth", testAuth, spec.Report(report.Terminal{}))
+}
+
+func testAuth(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory     *test.BuildFactory
+		contributor Contributor
+		root        string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+
+		root = test.ScratchDir(t, "bindings")
+		Expect(os.Setenv("SERVICE_BINDING_ROOT", root)).To(Succeed())
+
+		var err error
+		contributor, _, err = NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("SERVICE_BINDING_ROOT")).To(Succeed())
+	})
+
+	readAuth := func() map[string]map[string]interface{} {
+		buf, err := ioutil.ReadFile(contributor.authPath())
+		Expect(err).NotTo(HaveOccurred())
+
+		content := map[string]map[string]interface{}{}
+		Expect(json.Unmarshal(buf, &content)).To(Succeed())
+		return content
+	}
+
+	when("there are composer bindings", func() {
+		it("writes auth.json to COMPOSER_HOME", func() {
+			test.WriteFile(t, filepath.Join(root, "satis", "type"), "composer-auth")
+			test.WriteFile(t, filepath.Join(root, "satis", "host"), "satis.example.com")
+			test.WriteFile(t, filepath.Join(root, "satis", "username"), "user")
+			test.WriteFile(t, filepath.Join(root, "satis", "password"), "secret\n")
+
+			test.WriteFile(t, filepath.Join(root, "gitlab", "type"), "composer")
+			test.WriteFile(t, filepath.Join(root, "gitlab", "host"), "gitlab.example.com")
+			test.WriteFile(t, filepath.Join(root, "gitlab", "gitlab-token"), "glpat-token")
+
+			test.WriteFile(t, filepath.Join(root, "bitbucket", "type"), "composer")
+			test.WriteFile(t, filepath.Join(root, "bitbucket", "host"), "bitbucket.org")
+			test.WriteFile(t, filepath.Join(root, "bitbucket", "bitbucket-consumer-key"), "key")
+			test.WriteFile(t, filepath.Join(root, "bitbucket", "bitbucket-consumer-secret"), "consumer-secret")
+
+			test.WriteFile(t, filepath.Join(root, "complete", "type"), "composer-auth")
+			test.WriteFile(t, filepath.Join(root, "complete", "auth.json"), `{"bearer": {"repo.example.com": "bearer-token"}, "github-oauth": {"github.com": "gh-token"}}`)
+
+			test.WriteFile(t, filepath.Join(root, "database", "type"), "mysql")
+			test.WriteFile(t, filepath.Join(root, "database", "password"), "not-for-composer")
+
+			Expect(contributor.configureAuth()).To(Succeed())
+			Expect(contributor.authPath()).To(HavePrefix(contributor.composerLayer.Root))
+
+			info, err := os.Stat(contributor.authPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			Expect(readAuth()).To(Equal(map[string]map[string]interface{}{
+				"http-basic":      {"satis.example.com": map[string]interface{}{"username": "user", "password": "secret"}},
+				"gitlab-token":    {"gitlab.example.com": "glpat-token"},
+				"bitbucket-oauth": {"bitbucket.org": map[string]interface{}{"consumer-key": "key", "consumer-secret": "consumer-secret"}},
+				"bearer":          {"repo.example.com": "bearer-token"},
+				"github-oauth":    {"github.com": "gh-token"},
+			}))
+
+			Expect(contributor.removeAuth()).To(Succeed())
+			Expect(contributor.authPath()).NotTo(BeAnExistingFile())
+		})
+
+		it("fails when a host has no credentials", func() {
+			test.WriteFile(t, filepath.Join(root, "satis", "type"), "composer-auth")
+			test.WriteFile(t, filepath.Join(root, "satis", "host"), "satis.example.com")
+
+			Expect(contributor.configureAuth()).To(MatchError("unable to read credentials from binding satis: no credentials found for host satis.example.com"))
+		})
+	})
+
+	when("there are no composer bindings", func() {
+		it("does not write auth.json", func() {
+			Expect(contributor.configureAuth()).To(Succeed())
+			Expect(contributor.authPath()).NotTo(BeAnExistingFile())
+			Expect(contributor.removeAuth()).To(Succeed())
+		})
+	})
+}
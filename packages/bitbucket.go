@@ -0,0 +1,52 @@
+package packages
+
+import (
+	"net/http"
+
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// Bitbucket checks access tokens for bitbucket.org
+type Bitbucket struct {
+	apiURL string
+}
+
+// NewBitbucket creates a checker for bitbucket.org
+func NewBitbucket() Bitbucket {
+	return Bitbucket{apiURL: bitbucketAPIURL}
+}
+
+func (b Bitbucket) Name() string {
+	return "Bitbucket"
+}
+
+func (b Bitbucket) Host() string {
+	return "bitbucket.org"
+}
+
+// Check asks Bitbucket about the current user. Bitbucket reports the scopes of OAuth tokens but no quota.
+func (b Bitbucket) Check(token string) (TokenStatus, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+
+	resp, body, err := vcsRequest(b.apiURL+"/user", header)
+	if err != nil {
+		return TokenStatus{}, err
+	}
+	if err := checkResponse(resp, body); err != nil {
+		return TokenStatus{}, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return TokenStatus{}, nil
+	}
+
+	return TokenStatus{Valid: true, Scopes: splitScopes(resp.Header.Get("X-OAuth-Scopes"))}, nil
+}
+
+// Configure passes the access token as http-basic credentials for the x-token-auth user, as Bitbucket expects
+func (b Bitbucket) Configure(c composer.Composer, token string) error {
+	return c.ConfigList("http-basic."+b.Host(), []string{"x-token-auth", token}, true)
+}
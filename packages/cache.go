@@ -0,0 +1,131 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudfoundry/libcfbuildpack/layers"
+)
+
+// cacheMetadata identifies the Composer cache layer. It only changes when the layout of the layer changes, so the
+// cache is restored on every build instead of being recreated.
+var cacheMetadata = Metadata{Name: "PHP Composer Cache", Hash: "1"}
+
+func (c Contributor) cacheDir() string {
+	return filepath.Join(c.cacheLayer.Root, "cache")
+}
+
+func (c Contributor) contributeCache() error {
+	if c.composerConfig.ClearCache {
+		c.cacheLayer.Logger.Body("Clearing the Composer cache")
+		if err := os.RemoveAll(c.cacheDir()); err != nil {
+			return err
+		}
+	}
+
+	if err := c.cacheLayer.Contribute(cacheMetadata, func(layer layers.Layer) error { return nil }, layers.Cache); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(c.cacheDir(), os.ModePerm)
+}
+
+// maintainCache prunes the least recently used archives once the cache outgrows its maximum size and reports the
+// resulting size of the cache
+func (c Contributor) maintainCache() error {
+	files, size, err := cacheFiles(c.cacheDir())
+	if err != nil {
+		return err
+	}
+
+	if maxSize := c.composerConfig.CacheMaxSize; maxSize > 0 && size > maxSize {
+		removed, freed, err := pruneCache(files, size-maxSize)
+		if err != nil {
+			return err
+		}
+
+		c.cacheLayer.Logger.Body("Pruned %d stale files (%s) from the Composer cache", removed, formatSize(freed))
+		size -= freed
+		files = files[removed:]
+	}
+
+	c.cacheLayer.Logger.Body("Composer cache size: %s in %d files", formatSize(size), len(files))
+	return nil
+}
+
+type cacheFile struct {
+	path string
+	size int64
+	info os.FileInfo
+}
+
+// cacheFiles lists the archives in the Composer cache, least recently used first, and the total size of the cache
+func cacheFiles(dir string) ([]cacheFile, int64, error) {
+	var (
+		files []cacheFile
+		size  int64
+	)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+			files = append(files, cacheFile{path: path, size: info.Size(), info: info})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return accessTime(files[i].info).Before(accessTime(files[j].info))
+	})
+
+	return files, size, nil
+}
+
+// pruneCache removes files, in order, until at least the given number of bytes has been freed
+func pruneCache(files []cacheFile, bytes int64) (int, int64, error) {
+	var (
+		removed int
+		freed   int64
+	)
+
+	for _, file := range files {
+		if freed >= bytes {
+			break
+		}
+
+		if err := os.Remove(file.path); err != nil {
+			return removed, freed, err
+		}
+
+		removed++
+		freed += file.size
+	}
+
+	return removed, freed, nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
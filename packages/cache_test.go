@@ -0,0 +1,101 @@
+package packages
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitCache(t *testing.T) {
+	spec.Run(t, "Cache", testCache, spec.Report(report.Terminal{}))
+}
+
+func testCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory     *test.BuildFactory
+		contributor Contributor
+		info        *bytes.Buffer
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+
+		var err error
+		contributor, _, err = NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		info = &bytes.Buffer{}
+		contributor.cacheLayer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+	})
+
+	writeCacheFile := func(name string, size int, accessed time.Time) string {
+		path := filepath.Join(contributor.cacheDir(), "files", name)
+		test.WriteFile(t, path, string(make([]byte, size)))
+		Expect(os.Chtimes(path, accessed, accessed)).To(Succeed())
+		return path
+	}
+
+	when("the cache layer is contributed again", func() {
+		it("keeps the cached files", func() {
+			Expect(contributor.contributeCache()).To(Succeed())
+			cached := writeCacheFile("monolog/monolog/1234.zip", 10, time.Now())
+
+			Expect(contributor.contributeCache()).To(Succeed())
+			Expect(cached).To(BeARegularFile())
+			Expect(contributor.cacheLayer).To(test.HaveLayerMetadata(false, true, false))
+		})
+
+		it("removes the cached files when asked to clear the cache", func() {
+			Expect(contributor.contributeCache()).To(Succeed())
+			cached := writeCacheFile("monolog/monolog/1234.zip", 10, time.Now())
+
+			contributor.composerConfig.ClearCache = true
+			Expect(contributor.contributeCache()).To(Succeed())
+			Expect(cached).NotTo(BeAnExistingFile())
+			Expect(contributor.cacheDir()).To(BeADirectory())
+		})
+	})
+
+	when("the cache is larger than the maximum size", func() {
+		it("removes the least recently used files first", func() {
+			now := time.Now()
+			oldest := writeCacheFile("a/a/old.zip", 1024, now.Add(-3*time.Hour))
+			older := writeCacheFile("b/b/older.zip", 1024, now.Add(-2*time.Hour))
+			recent := writeCacheFile("c/c/recent.zip", 1024, now)
+
+			contributor.composerConfig.CacheMaxSize = 1500
+			Expect(contributor.maintainCache()).To(Succeed())
+
+			Expect(oldest).NotTo(BeAnExistingFile())
+			Expect(older).NotTo(BeAnExistingFile())
+			Expect(recent).To(BeARegularFile())
+			Expect(info.String()).To(ContainSubstring("Pruned 2 stale files (2.0 KiB) from the Composer cache"))
+			Expect(info.String()).To(ContainSubstring("Composer cache size: 1.0 KiB in 1 files"))
+		})
+	})
+
+	when("the cache is within the maximum size", func() {
+		it("only reports the size", func() {
+			cached := writeCacheFile("a/a/1.zip", 100, time.Now())
+
+			contributor.composerConfig.CacheMaxSize = 1024
+			Expect(contributor.maintainCache()).To(Succeed())
+
+			Expect(cached).To(BeARegularFile())
+			Expect(info.String()).To(Equal("    Composer cache size: 100 B in 1 files\n"))
+		})
+	})
+}
@@ -2,26 +2,25 @@ package packages
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/buildpack/libbuildpack/application"
 	"github.com/cloudfoundry/libcfbuildpack/build"
 	"github.com/cloudfoundry/libcfbuildpack/helper"
 	"github.com/cloudfoundry/libcfbuildpack/layers"
 	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/manifest"
 	"github.com/paketo-buildpacks/php-web/config"
 )
 
 type Metadata struct {
-	Name string
-	Hash string
+	Name       string
+	Hash       string
+	Autoloader string `toml:",omitempty"`
 }
 
 func (m Metadata) Identity() (name string, version string) {
@@ -33,20 +32,23 @@ type Contributor struct {
 	composerLayer         layers.Layer
 	composerPackagesLayer layers.Layer
 	cacheLayer            layers.Layer
+	auditLayer            layers.Layer
 	composerMetadata      Metadata
 	composer              composer.Composer
-	composerBuildpackYAML composer.BuildpackYAML
+	composerConfig        composer.Config
+	scripts               manifest.Scripts
+	composerJSONPath      string
+	buildpackVersion      string
+	name                  string
+	vendorRoot            string
+	projects              []Contributor
+	deadline              time.Time
 }
 
-func generateRandomHash() [32]byte {
-	randBuf := make([]byte, 512)
-	rand.Read(randBuf)
-	return sha256.Sum256(randBuf)
-}
-
-// NewContributor creates a new "packages" contributor for installing Composer packages
+// NewContributor creates a new "packages" contributor for installing Composer packages. Every project listed in
+// BP_COMPOSER_PROJECTS gets its own packages layer, and the contributor itself describes the first project.
 func NewContributor(context build.Build, composerPharPath string) (Contributor, bool, error) {
-	buildpackYAML, err := composer.LoadComposerBuildpackYAML(context.Application.Root)
+	cfg, err := composer.LoadConfig(context.Application.Root)
 	if err != nil {
 		return Contributor{}, false, err
 	}
@@ -56,47 +58,129 @@ func NewContributor(context build.Build, composerPharPath string) (Contributor,
 		return Contributor{}, false, err
 	}
 
-	path, err := composer.FindComposer(context.Application.Root, buildpackYAML.Composer.JsonPath)
+	cfg.Log(context.Logger)
+
+	paths, err := composer.FindProjects(context.Application.Root, cfg)
 	if err != nil {
 		return Contributor{}, false, err
 	}
 
-	composerDir := filepath.Dir(path)
-	lockPath := filepath.Join(composerDir, composer.ComposerLock)
-	var hash [32]byte
-	if exists, err := helper.FileExists(lockPath); err != nil {
-		return Contributor{}, false, err
-	} else if exists {
-		buf, err := ioutil.ReadFile(lockPath)
+	app := Contributor{
+		app:              context.Application,
+		composerLayer:    context.Layers.Layer(composer.Dependency),
+		cacheLayer:       context.Layers.Layer(composer.CacheDependency),
+		composerConfig:   cfg,
+		buildpackVersion: context.Buildpack.Info.Version,
+		deadline:         cfg.Deadline(time.Now()),
+	}
+
+	var projects []Contributor
+	layerNames := map[string]string{}
+	for _, path := range paths {
+		project, err := app.project(context, path, composerPharPath)
 		if err != nil {
 			return Contributor{}, false, err
 		}
 
-		hash = sha256.Sum256(buf)
-	} else {
-		hash = generateRandomHash()
-	}
+		if other, ok := layerNames[project.name]; ok {
+			return Contributor{}, false, fmt.Errorf("projects %s and %s would share the layer %s, rename one of their directories", other, project.projectDir(), project.name)
+		}
+		layerNames[project.name] = project.projectDir()
 
-	contributor := Contributor{
-		app:                   context.Application,
-		composerLayer:         context.Layers.Layer(composer.Dependency),
-		composerPackagesLayer: context.Layers.Layer(composer.PackagesDependency),
-		cacheLayer:            context.Layers.Layer(composer.CacheDependency),
-		composerMetadata:      Metadata{"PHP Composer", hex.EncodeToString(hash[:])},
-		composer:              composer.NewComposer(composerDir, composerPharPath, context.Logger),
-		composerBuildpackYAML: buildpackYAML,
+		projects = append(projects, project)
 	}
 
-	if err := contributor.initializeEnv(buildpackYAML.Composer.VendorDirectory); err != nil {
+	contributor := projects[0]
+	contributor.projects = projects
+
+	if err := contributor.initializeEnv(); err != nil {
 		return Contributor{}, false, err
 	}
 
 	return contributor, true, nil
 }
 
+// project returns a copy of the contributor for the Composer project at path. A single project and the app root keep
+// the layer names they have always had, while the other listed projects get layers named after their directory. Listed
+// projects get a vendor directory next to their composer.json.
+func (c Contributor) project(context build.Build, path, composerPharPath string) (Contributor, error) {
+	if err := composer.ValidateLock(path, c.composerConfig.LockValidation, context.Logger); err != nil {
+		return Contributor{}, err
+	}
+
+	composerJSON, err := manifest.ReadManifest(path)
+	if err != nil {
+		return Contributor{}, err
+	}
+
+	key, err := newPackagesKey(path, filepath.Join(composerPharPath, composer.ComposerPHAR), c.composerConfig)
+	if err != nil {
+		return Contributor{}, err
+	}
+
+	hash, err := key.hash()
+	if err != nil {
+		return Contributor{}, err
+	}
+
+	c.name = composer.PackagesDependency
+	auditName := composer.AuditDependency
+	c.vendorRoot = c.app.Root
+	if len(c.composerConfig.Projects) > 0 {
+		if slug := projectSlug(c.app.Root, path); slug != "" {
+			c.name += "-" + slug
+			auditName += "-" + slug
+		}
+		c.vendorRoot = filepath.Dir(path)
+	}
+
+	c.composerPackagesLayer = context.Layers.Layer(c.name)
+	c.auditLayer = context.Layers.Layer(auditName)
+	c.composerMetadata = Metadata{Name: "PHP Composer", Hash: hash, Autoloader: c.composerConfig.Autoloader}
+	c.composer = composer.NewComposerForConfig(filepath.Dir(path), composerPharPath, context.Logger, c.composerConfig)
+	c.composer.Deadline = c.deadline
+	c.scripts = composerJSON.Scripts
+	c.composerJSONPath = path
+
+	return c, nil
+}
+
+// projectSlug names a project after its directory relative to the app root, e.g. tools-cli for tools/cli. The app
+// root has no name.
+func projectSlug(appRoot, composerJSONPath string) string {
+	rel, err := filepath.Rel(appRoot, filepath.Dir(composerJSONPath))
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == filepath.Separator || r == '.' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, strings.ToLower(rel))
+}
+
+// allProjects returns every project in order, with the contributor itself standing in for the first one
+func (c Contributor) allProjects() []Contributor {
+	if len(c.projects) == 0 {
+		return []Contributor{c}
+	}
+	return append([]Contributor{c}, c.projects[1:]...)
+}
+
+// projectDir returns the directory of the project relative to the app root
+func (c Contributor) projectDir() string {
+	rel, err := filepath.Rel(c.app.Root, filepath.Dir(c.composerJSONPath))
+	if err != nil {
+		return filepath.Dir(c.composerJSONPath)
+	}
+	return rel
+}
+
 func (c Contributor) SetupVendorDir() error {
-	composerLayerVendorDir := filepath.Join(c.composerPackagesLayer.Root, c.composerBuildpackYAML.Composer.VendorDirectory)
-	composerAppVendorDir := filepath.Join(c.app.Root, c.composerBuildpackYAML.Composer.VendorDirectory)
+	composerLayerVendorDir := filepath.Join(c.composerPackagesLayer.Root, c.composerConfig.VendorDirectory)
+	composerAppVendorDir := filepath.Join(c.vendorRoot, c.composerConfig.VendorDirectory)
 
 	exists, err := helper.FileExists(composerAppVendorDir)
 	if err != nil {
@@ -115,55 +199,97 @@ func (c Contributor) SetupVendorDir() error {
 }
 
 func (c Contributor) Contribute() error {
-	randomHash := generateRandomHash()
-	if err := c.cacheLayer.Contribute(Metadata{"PHP Composer Cache", hex.EncodeToString(randomHash[:])}, func(layer layers.Layer) error { return nil }, layers.Cache); err != nil {
+	if err := c.contributeCache(); err != nil {
 		return err
 	}
 
-	if err := c.alwaysRunComposerInit(c.composerPackagesLayer); err != nil {
+	// the credentials must not outlive the build, even when it fails
+	if err := c.configureAuth(); err != nil {
 		return err
 	}
+	defer func() {
+		if err := c.removeAuth(); err != nil {
+			c.composer.Logger.BodyWarning("Unable to remove the credentials in %s: %s", c.authPath(), err)
+		}
+	}()
+	defer func() {
+		if err := c.removeRepositories(); err != nil {
+			c.composer.Logger.BodyWarning("Unable to remove the repository configuration: %s", err)
+		}
+	}()
 
-	if err := c.SetupVendorDir(); err != nil {
+	if err := c.alwaysRunComposerInit(c.composerPackagesLayer); err != nil {
 		return err
 	}
 
-	return c.composerPackagesLayer.Contribute(c.composerMetadata, c.contributeComposerPackages, layers.Launch)
-}
+	projects := c.allProjects()
+	for _, project := range projects {
+		if len(projects) > 1 {
+			c.composer.Logger.Header("Project %s", project.projectDir())
+		}
 
-func (c Contributor) configureGithubOauthToken() error {
-	githubOauthToken := os.Getenv("COMPOSER_GITHUB_OAUTH_TOKEN")
-	if githubOauthToken != "" {
-		github, err := NewDefaultGithub(githubOauthToken)
-		if err != nil {
+		start := time.Now()
+		if err := project.contributeProject(); err != nil {
+			if len(projects) > 1 {
+				return fmt.Errorf("project %s: %w", project.projectDir(), err)
+			}
 			return err
 		}
 
-		if ok, err := github.validateToken(); err != nil {
+		if len(projects) > 1 {
+			c.composer.Logger.Body("Finished project %s in %s", project.projectDir(), time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	return c.maintainCache()
+}
+
+// contributeProject installs the packages of a single project and checks them against the license policy and
+// security advisories
+func (c Contributor) contributeProject() error {
+	dirs, err := c.pathRepositories()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		c.composer.Logger.Body("Using path repository %s", dir)
+	}
+
+	if err := c.setAppVendorDir(); err != nil {
+		return err
+	}
+
+	if err := c.SetupVendorDir(); err != nil {
+		return err
+	}
+
+	// the packages of composer.lock are checked before they are installed, the others only once they are
+	locked, err := c.hasLock()
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		if err := c.enforceLicensePolicy(); err != nil {
 			return err
-		} else if ok {
-			if err := c.composer.Config("github-oauth.github.com", githubOauthToken, true); err != nil {
-				return err
-			}
 		}
+	}
+
+	if err := c.composerPackagesLayer.Contribute(c.composerMetadata, c.contributeComposerPackages, layers.Launch); err != nil {
+		return err
+	}
 
-		if ok, err := github.checkRateLimit(); err != nil {
+	if !locked {
+		if err := c.enforceLicensePolicy(); err != nil {
 			return err
-		} else if !ok {
-			c.composer.Logger.BodyWarning("The GitHub api rate limit has been exceeded. " +
-				"Composer will continue by downloading from source, which might result in slower downloads. " +
-				"You can increase your rate limit with a GitHub OAuth token. " +
-				"Please obtain a GitHub OAuth token by registering your application at " +
-				"https://github.com/settings/applications/new. " +
-				"Then set COMPOSER_GITHUB_OAUTH_TOKEN in your environment to the value of this token.")
 		}
 	}
 
-	return nil
+	return c.auditPackages()
 }
 
 func (c Contributor) installGlobalPackages() error {
-	if len(c.composerBuildpackYAML.Composer.InstallGlobal) > 0 {
+	if len(c.composerConfig.InstallGlobal) > 0 {
 		binPath := strings.Join([]string{os.Getenv("PATH"), filepath.Join(c.composerPackagesLayer.Root, "global/vendor/bin")}, string(os.PathListSeparator))
 		err := os.Setenv("PATH", binPath)
 		if err != nil {
@@ -174,7 +300,7 @@ func (c Contributor) installGlobalPackages() error {
 			return err
 		}
 
-		if err := c.composer.Global(c.composerBuildpackYAML.Composer.InstallGlobal...); err != nil {
+		if err := c.composer.Global(c.composerConfig.InstallGlobal...); err != nil {
 			return err
 		}
 	}
@@ -182,16 +308,45 @@ func (c Contributor) installGlobalPackages() error {
 }
 
 func (c Contributor) alwaysRunComposerInit(layer layers.Layer) error {
-	phpExtensions, err := c.composer.CheckPlatformReqs()
-	if err != nil {
-		return err
+	var checkOptions []string
+	if composer.Contains(c.composerConfig.InstallOptions, "--no-dev") {
+		checkOptions = append(checkOptions, "--no-dev")
+	}
+
+	var phpExtensions []string
+	projects := c.allProjects()
+	for _, project := range projects {
+		if len(projects) > 1 {
+			c.composer.Logger.Header("Checking project %s", project.projectDir())
+		}
+
+		requirements, err := project.composer.PlatformRequirements(checkOptions...)
+		if err != nil {
+			return err
+		}
+		project.logPlatformRequirements(requirements)
+
+		extensions, err := project.loadableExtensions(requirements)
+		if err != nil {
+			return err
+		}
+
+		for _, extension := range extensions {
+			if !composer.Contains(phpExtensions, extension) {
+				phpExtensions = append(phpExtensions, extension)
+			}
+		}
 	}
 
 	if err := c.enablePHPExtensions(phpExtensions); err != nil {
 		return err
 	}
 
-	if err := c.configureGithubOauthToken(); err != nil {
+	if err := c.configureRepositories(); err != nil {
+		return err
+	}
+
+	if err := c.configureVCSTokens(); err != nil {
 		return err
 	}
 
@@ -199,7 +354,7 @@ func (c Contributor) alwaysRunComposerInit(layer layers.Layer) error {
 		return err
 	}
 
-	err = c.warnAboutPublicComposerFiles(layer)
+	err := c.warnAboutPublicComposerFiles(layer)
 	if err != nil {
 		return err
 	}
@@ -212,14 +367,36 @@ func (c Contributor) contributeComposerPackages(layer layers.Layer) error {
 		return err
 	}
 
-	return c.composer.Install(c.composerBuildpackYAML.Composer.InstallOptions...)
+	restoreLock, err := c.useArtifacts()
+	if err != nil {
+		return err
+	}
+
+	if err := c.installPackages(); err != nil {
+		_ = restoreLock()
+		return err
+	}
+
+	if err := restoreLock(); err != nil {
+		return err
+	}
+
+	if err := c.relinkPathPackages(filepath.Join(layer.Root, c.composerConfig.VendorDirectory)); err != nil {
+		return err
+	}
+
+	return c.writeSBOM(layer)
 }
 
 func (c Contributor) enablePHPExtensions(extensions []string) error {
 	buf := bytes.Buffer{}
 
 	for _, extension := range extensions {
-		buf.WriteString(fmt.Sprintf("extension = %s.so\n", extension))
+		directive := "extension"
+		if composer.ZendExtensions[extension] {
+			directive = "zend_extension"
+		}
+		buf.WriteString(fmt.Sprintf("%s = %s.so\n", directive, extension))
 	}
 
 	return helper.WriteFile(filepath.Join(c.app.Root, ".php.ini.d", "composer-extensions.ini"), 0655, buf.String())
@@ -249,7 +426,7 @@ func (c Contributor) warnAboutPublicComposerFiles(layer layers.Layer) error {
 	return nil
 }
 
-func (c Contributor) initializeEnv(vendorDirectory string) error {
+func (c Contributor) initializeEnv() error {
 	// override anything possibly set by the user
 	err := os.Setenv("COMPOSER_HOME", filepath.Join(c.composerLayer.Root, ".composer"))
 	if err != nil {
@@ -272,13 +449,30 @@ func (c Contributor) initializeEnv(vendorDirectory string) error {
 		return err
 	}
 
+	// Composer raises its memory limit to 1.5G unless COMPOSER_MEMORY_LIMIT is set, which would override a lower limit
+	if limit, ok := c.composerConfig.PHPIni["memory_limit"]; ok && os.Getenv("COMPOSER_MEMORY_LIMIT") == "" {
+		err = os.Setenv("COMPOSER_MEMORY_LIMIT", limit)
+		if err != nil {
+			return err
+		}
+	}
+
+	// COMPOSER is resolved relative to the app root, so it must not leak into Composer itself
+	err = os.Unsetenv("COMPOSER")
+	if err != nil {
+		return err
+	}
+
 	err = os.Setenv("PHP_INI_SCAN_DIR", filepath.Join(c.app.Root, ".php.ini.d"))
 	if err != nil {
 		return err
 	}
 
-	binPath := strings.Join([]string{os.Getenv("PATH"), filepath.Join(c.app.Root, c.composerBuildpackYAML.Composer.VendorDirectory, "bin")}, string(os.PathListSeparator))
-	err = os.Setenv("PATH", binPath)
+	binPaths := []string{os.Getenv("PATH")}
+	for _, project := range c.allProjects() {
+		binPaths = append(binPaths, filepath.Join(project.vendorRoot, c.composerConfig.VendorDirectory, "bin"))
+	}
+	err = os.Setenv("PATH", strings.Join(binPaths, string(os.PathListSeparator)))
 	if err != nil {
 		return err
 	}
@@ -295,7 +489,7 @@ func (c Contributor) setGlobalVendorDir() error {
 }
 
 func (c Contributor) setAppVendorDir() error {
-	err := os.Setenv("COMPOSER_VENDOR_DIR", filepath.Join(c.composerPackagesLayer.Root, c.composerBuildpackYAML.Composer.VendorDirectory))
+	err := os.Setenv("COMPOSER_VENDOR_DIR", filepath.Join(c.composerPackagesLayer.Root, c.composerConfig.VendorDirectory))
 	if err != nil {
 		return err
 	}
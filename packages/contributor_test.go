@@ -3,7 +3,7 @@ package packages
 import (
 	"bytes"
 	"io/ioutil"
-	"math/rand"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -13,6 +13,7 @@ import (
 	"github.com/cloudfoundry/libcfbuildpack/logger"
 	"github.com/cloudfoundry/libcfbuildpack/test"
 	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/runner"
 
 	. "github.com/onsi/gomega"
 	"github.com/sclevine/spec"
@@ -32,50 +33,114 @@ func testComposerPackage(t *testing.T, when spec.G, it spec.S) {
 	})
 
 	when("NewContributor", func() {
+		var composerJSONPath, composerLockPath string
+
 		it.Before(func() {
-			composerJSONString := `{something:this is a json file}`
-			composerJSONPath := filepath.Join(factory.Build.Application.Root, composer.ComposerJSON)
-			test.WriteFile(t, composerJSONPath, composerJSONString)
+			composerJSONPath = filepath.Join(factory.Build.Application.Root, composer.ComposerJSON)
+			test.WriteFile(t, composerJSONPath, `{"require": {"monolog/monolog": "^1.24"}}`)
+			composerLockPath = filepath.Join(factory.Build.Application.Root, composer.ComposerLock)
 		})
 
+		newHash := func() string {
+			contributor, willContribute, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(willContribute).To(BeTrue())
+			Expect(contributor.composerMetadata.Name).To(Equal("PHP Composer"))
+			Expect(contributor.composerMetadata.Hash).To(HaveLen(64))
+			return contributor.composerMetadata.Hash
+		}
+
 		when("there is a lock file", func() {
-			it("includes a hash of the lock file in the composer metadata", func() {
-				composerLockString := `this is a lock file`
-				composerLockPath := filepath.Join(factory.Build.Application.Root, composer.ComposerLock)
-				test.WriteFile(t, composerLockPath, composerLockString)
+			it("derives the hash from the resolved packages", func() {
+				test.WriteFile(t, composerLockPath, `{"_readme": ["one"], "packages": [{"name": "monolog/monolog", "version": "1.25.1", "dist": {"reference": "70e65a5"}, "time": "2019-09-06T13:49:17+00:00"}]}`)
+				hash := newHash()
 
-				contributor, willContribute, err := NewContributor(factory.Build, "/tmp")
-				Expect(err).NotTo(HaveOccurred())
-				Expect(willContribute).To(BeTrue())
-				Expect(contributor.composerMetadata.Name).To(Equal("PHP Composer"))
-				Expect(contributor.composerMetadata.Hash).To(Equal("fe2ebd62604e50ad1682fb67979fd368375c2347973c47af8b0394a5359e3e08"))
+				test.WriteFile(t, composerLockPath, `{"_readme": ["two"], "packages": [{"name": "monolog/monolog", "version": "1.25.1", "dist": {"reference": "70e65a5"}, "time": "2019-09-07T00:00:00+00:00"}]}`)
+				Expect(newHash()).To(Equal(hash))
+
+				test.WriteFile(t, composerLockPath, `{"packages": [{"name": "monolog/monolog", "version": "1.25.2", "dist": {"reference": "f9d56fd"}}]}`)
+				Expect(newHash()).NotTo(Equal(hash))
+			})
+
+			it("ignores dev packages when they are not installed", func() {
+				test.WriteFile(t, composerLockPath, `{"packages": [{"name": "monolog/monolog", "version": "1.25.1"}]}`)
+				hash := newHash()
+
+				test.WriteFile(t, composerLockPath, `{"packages": [{"name": "monolog/monolog", "version": "1.25.1"}], "packages-dev": [{"name": "phpunit/phpunit", "version": "8.5.0"}]}`)
+				Expect(newHash()).To(Equal(hash))
 			})
 		})
 
 		when("there isn't a lock file", func() {
-			it("randomly generates a hash for composer metadata", func() {
-				// Caution: Not thread-safe; may cause test pollution
-				rand.Seed(1)
+			it("derives the hash from composer.json", func() {
+				hash := newHash()
+				Expect(newHash()).To(Equal(hash))
 
-				contributor, willContribute, err := NewContributor(factory.Build, "/tmp")
+				test.WriteFile(t, composerJSONPath, `{"description": "cosmetic", "require": {"monolog/monolog": "^1.24"}}`)
+				Expect(newHash()).To(Equal(hash))
+
+				test.WriteFile(t, composerJSONPath, `{"require": {"monolog/monolog": "^2.0"}}`)
+				Expect(newHash()).NotTo(Equal(hash))
+			})
+		})
+
+		when("the install options change", func() {
+			it.After(func() {
+				Expect(os.Unsetenv(composer.InstallOptionsEnv)).To(Succeed())
+			})
+
+			it("changes the hash", func() {
+				hash := newHash()
+
+				Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev --optimize-autoloader")).To(Succeed())
+				Expect(newHash()).NotTo(Equal(hash))
+			})
+		})
+
+		when("the autoloader mode changes", func() {
+			it.After(func() {
+				Expect(os.Unsetenv(composer.AutoloaderEnv)).To(Succeed())
+			})
+
+			it("changes the hash and records the mode", func() {
+				hash := newHash()
+
+				Expect(os.Setenv(composer.AutoloaderEnv, composer.AutoloaderAPCu)).To(Succeed())
+				Expect(newHash()).NotTo(Equal(hash))
+
+				contributor, _, err := NewContributor(factory.Build, "/tmp")
 				Expect(err).NotTo(HaveOccurred())
-				Expect(willContribute).To(BeTrue())
-				Expect(contributor.composerMetadata.Name).To(Equal("PHP Composer"))
-				// TODO confirm the following doesn't change across different computers
-				Expect(contributor.composerMetadata.Hash).To(Equal("96ebbb5c8694dd2c33b07ca6d40c10b0b670bc10176d2507d8b3b4a739d46f01"))
+				Expect(contributor.composerMetadata.Autoloader).To(Equal(composer.AutoloaderAPCu))
 			})
 		})
 	})
 
+	when("a memory limit is set for Composer", func() {
+		it.After(func() {
+			for _, env := range []string{composer.PHPIniEnv("memory_limit"), "COMPOSER_MEMORY_LIMIT"} {
+				Expect(os.Unsetenv(env)).To(Succeed())
+			}
+		})
+
+		it("keeps Composer from raising it", func() {
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+			Expect(os.Setenv(composer.PHPIniEnv("memory_limit"), "512M")).To(Succeed())
+
+			_, _, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Getenv("COMPOSER_MEMORY_LIMIT")).To(Equal("512M"))
+		})
+	})
+
 	when("there is a lock file in WEBDIR", func() {
 		it("should warn about the file being publicly accessible", func() {
 			webdir := "htdocs"
 
 			// write out composer.json & composer.lock
 			composerJSONPath := filepath.Join(factory.Build.Application.Root, webdir, composer.ComposerJSON)
-			test.WriteFile(t, composerJSONPath, "does not matter")
+			test.WriteFile(t, composerJSONPath, "{}")
 			composerLockPath := filepath.Join(factory.Build.Application.Root, webdir, composer.ComposerLock)
-			test.WriteFile(t, composerLockPath, "does not matter")
+			test.WriteFile(t, composerLockPath, "{}")
 
 			// write out buildpack.yml
 			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "buildpack.yml"), `{"php": {"webdirectory": "htdocs"}}`)
@@ -102,7 +167,7 @@ func testComposerPackage(t *testing.T, when spec.G, it spec.S) {
 
 	when("enabling php extensions", func() {
 		it("adds each extension to the .php.ini.d file", func() {
-			Expect(helper.WriteFile(filepath.Join(factory.Build.Application.Root, "composer.json"), 0644, "")).ToNot(HaveOccurred())
+			Expect(helper.WriteFile(filepath.Join(factory.Build.Application.Root, "composer.json"), 0644, "{}")).ToNot(HaveOccurred())
 
 			phpinid := filepath.Join(factory.Build.Application.Root, ".php.ini.d")
 			composer_exts := filepath.Join(phpinid, "composer-extensions.ini")
@@ -122,7 +187,7 @@ func testComposerPackage(t *testing.T, when spec.G, it spec.S) {
 
 	when("The vendor folder already exists", func() {
 		it("moves it to a layer & links it ", func() {
-			Expect(helper.WriteFile(filepath.Join(factory.Build.Application.Root, "composer.json"), 0644, "")).ToNot(HaveOccurred())
+			Expect(helper.WriteFile(filepath.Join(factory.Build.Application.Root, "composer.json"), 0644, "{}")).ToNot(HaveOccurred())
 
 			vendoredFile := filepath.Join(factory.Build.Application.Root, "vendor", "vendored_file.txt")
 			Expect(helper.WriteFile(vendoredFile, 0644, "stuff")).ToNot(HaveOccurred())
@@ -138,4 +203,125 @@ func testComposerPackage(t *testing.T, when spec.G, it spec.S) {
 		})
 
 	})
+
+	when("there are several projects", func() {
+		it.Before(func() {
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "tools", "cli", composer.ComposerJSON), "{}")
+			Expect(os.Setenv(composer.ProjectsEnv, ".,tools/cli")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv(composer.ProjectsEnv)).To(Succeed())
+		})
+
+		it("gives every project its own layers and vendor directory", func() {
+			contributor, willContribute, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(willContribute).To(BeTrue())
+
+			projects := contributor.allProjects()
+			Expect(projects).To(HaveLen(2))
+			Expect(projects[0].name).To(Equal("php-composer-packages"))
+			Expect(projects[0].auditLayer.Root).To(HaveSuffix("php-composer-audit"))
+			Expect(projects[1].name).To(Equal("php-composer-packages-tools-cli"))
+			Expect(projects[1].projectDir()).To(Equal(filepath.Join("tools", "cli")))
+			Expect(projects[1].composerPackagesLayer.Root).To(HaveSuffix("php-composer-packages-tools-cli"))
+
+			Expect(projects[1].SetupVendorDir()).To(Succeed())
+			link, err := os.Readlink(filepath.Join(factory.Build.Application.Root, "tools", "cli", "vendor"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal(filepath.Join(projects[1].composerPackagesLayer.Root, "vendor")))
+		})
+
+		it("adds the bin directory of every project to the PATH", func() {
+			_, _, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+
+			path := filepath.SplitList(os.Getenv("PATH"))
+			Expect(path).To(ContainElement(filepath.Join(factory.Build.Application.Root, "vendor", "bin")))
+			Expect(path).To(ContainElement(filepath.Join(factory.Build.Application.Root, "tools", "cli", "vendor", "bin")))
+		})
+
+		it("fails when two projects would share a layer", func() {
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "tools-cli", composer.ComposerJSON), "{}")
+			Expect(os.Setenv(composer.ProjectsEnv, ".,tools/cli,tools-cli")).To(Succeed())
+
+			_, _, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).To(MatchError("projects tools/cli and tools-cli would share the layer php-composer-packages-tools-cli, rename one of their directories"))
+		})
+	})
+
+	when("contributing with a scripted runner", func() {
+		var scripted *runner.ScriptedRunner
+
+		it.Before(func() {
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), `{"require": {"monolog/monolog": "^1.24"}}`)
+			Expect(os.Setenv(composer.MirrorEnv, "https://packagist.example.com")).To(Succeed())
+			Expect(os.Setenv(composer.GlobalInstallOptionsEnv, "friendsofphp/php-cs-fixer")).To(Succeed())
+
+			scripted = &runner.ScriptedRunner{Ordered: true}
+		})
+
+		it.After(func() {
+			for _, env := range []string{composer.MirrorEnv, composer.GlobalInstallOptionsEnv, composer.VendorDirectoryEnv} {
+				Expect(os.Unsetenv(env)).To(Succeed())
+			}
+		})
+
+		contribute := func() error {
+			contributor, _, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+
+			contributor.composer.Runner = scripted
+			return contributor.Contribute()
+		}
+
+		it("runs the Composer commands in order", func() {
+			scripted.Steps = []runner.Step{
+				{Match: []string{"php", "check-platform-reqs", "--no-dev"}, Stdout: "php 7.4.0 success\n"},
+				{Match: []string{"php", "config", "-g", "repositories.packagist.org", `{"type":"composer","url":"https://packagist.example.com"}`}},
+				{Match: []string{"php", "global", "require", "--no-progress", "friendsofphp/php-cs-fixer"}},
+				{Match: []string{"php", "install", "--no-progress", "--no-dev", "-v"}},
+			}
+
+			Expect(contribute()).To(Succeed())
+			Expect(scripted.Pending()).To(BeEmpty())
+			Expect(scripted.Calls()).To(HaveLen(4))
+		})
+
+		it("removes the credentials when the setup fails", func() {
+			root := test.ScratchDir(t, "bindings")
+			Expect(os.Setenv("SERVICE_BINDING_ROOT", root)).To(Succeed())
+			defer os.Unsetenv("SERVICE_BINDING_ROOT")
+			test.WriteFile(t, filepath.Join(root, "satis", "type"), "composer-auth")
+			test.WriteFile(t, filepath.Join(root, "satis", "host"), "satis.example.com")
+			test.WriteFile(t, filepath.Join(root, "satis", "username"), "user")
+			test.WriteFile(t, filepath.Join(root, "satis", "password"), "secret")
+
+			scripted.Steps = []runner.Step{
+				{Match: []string{"php", "check-platform-reqs", "--no-dev"}, Stdout: "php 7.4.0 success\n"},
+				{Match: []string{"php", "config", "-g", "repositories.packagist.org", `{"type":"composer","url":"https://packagist.example.com"}`}},
+				{Match: []string{"php", "global", "require", "--no-progress", "friendsofphp/php-cs-fixer"}, ExitCode: 1},
+			}
+
+			Expect(contribute()).To(MatchError(ContainSubstring("global require")))
+			Expect(filepath.Join(os.Getenv("COMPOSER_HOME"), "auth.json")).NotTo(BeAnExistingFile())
+		})
+
+		it("classifies the failure of the install", func() {
+			scripted.Steps = []runner.Step{
+				{Match: []string{"php", "check-platform-reqs", "--no-dev"}, Stdout: "php 7.4.0 success\n"},
+				{Match: []string{"php", "config", "-g", "repositories.packagist.org", `{"type":"composer","url":"https://packagist.example.com"}`}},
+				{Match: []string{"php", "global", "require", "--no-progress", "friendsofphp/php-cs-fixer"}},
+				{Match: []string{"php", "install", "--no-progress", "--no-dev", "-v"}, Stderr: "Your requirements could not be resolved to an installable set of packages.\n\n  Problem 1\n", ExitCode: 2},
+			}
+
+			err := contribute()
+			failure, ok := composer.ClassifyFailure(err)
+			Expect(ok).To(BeTrue())
+			Expect(failure.Code).To(Equal(composer.FailureResolutionCode))
+			Expect(failure.Excerpt).To(Equal([]string{"Your requirements could not be resolved to an installable set of packages.", "  Problem 1"}))
+		})
+	})
 }
@@ -0,0 +1,227 @@
+package packages
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+// How a required extension can be provided
+const (
+	ExtensionBuiltIn     = "built-in"
+	ExtensionLoadable    = "loadable"
+	ExtensionUnavailable = "unavailable"
+)
+
+// extensionModules maps Composer extension names to the name of their shared object, where they differ
+var extensionModules = map[string]string{
+	"zend-opcache": "opcache",
+}
+
+// polyfills are packages that implement an extension in PHP
+var polyfills = map[string]string{
+	"ctype":    "symfony/polyfill-ctype",
+	"iconv":    "symfony/polyfill-iconv",
+	"intl":     "symfony/polyfill-intl-icu",
+	"mbstring": "symfony/polyfill-mbstring",
+	"uuid":     "symfony/polyfill-uuid",
+}
+
+type extension struct {
+	name         string
+	module       string
+	availability string
+	requirement  composer.PlatformRequirement
+}
+
+// phpExtensionDir returns the directory PHP loads shared extensions from, following the extension_dir of php-web's
+// php.ini template. It is empty when PHP is not known.
+func phpExtensionDir() string {
+	if dir := os.Getenv("PHP_EXTENSION_DIR"); dir != "" {
+		return dir
+	}
+
+	home, api := os.Getenv("PHP_HOME"), os.Getenv("PHP_API")
+	if home == "" || api == "" {
+		return ""
+	}
+	return filepath.Join(home, "lib", "php", "extensions", fmt.Sprintf("no-debug-non-zts-%s", api))
+}
+
+// loadableExtensions classifies every required extension as built into PHP, loadable from the extension directory or
+// unavailable. It returns the loadable extensions Composer reported as missing and fails when an extension is
+// unavailable, instead of enabling a shared object that does not exist.
+func (c Contributor) loadableExtensions(requirements composer.PlatformRequirements) ([]string, error) {
+	dir := phpExtensionDir()
+	exists, err := helper.FileExists(dir)
+	if err != nil {
+		return nil, err
+	} else if dir == "" || !exists {
+		c.composer.Logger.Debug("Unable to find the PHP extension directory, enabling all missing extensions")
+		return requirements.MissingExtensions(), nil
+	}
+
+	available, err := sharedObjects(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := classifyExtensions(requirements, available)
+	if len(extensions) == 0 {
+		return []string{}, nil
+	}
+
+	logger := c.composer.Logger
+	logger.Header("PHP extensions")
+
+	load := []string{}
+	var unavailable []extension
+	for _, ext := range extensions {
+		switch {
+		case ext.availability == ExtensionUnavailable:
+			unavailable = append(unavailable, ext)
+			logger.BodyError("%s: %s", ext.name, ext.availability)
+		case ext.availability == ExtensionLoadable && ext.requirement.Status == composer.PlatformStatusMissing:
+			load = append(load, ext.module)
+			logger.Body("%s: %s, enabling %s.so", ext.name, ext.availability, ext.module)
+		default:
+			logger.Body("%s: %s", ext.name, ext.availability)
+		}
+	}
+
+	if len(unavailable) > 0 {
+		return nil, unavailableExtensionsError(unavailable, dir, available)
+	}
+
+	return load, nil
+}
+
+// classifyExtensions decides how each required extension is provided. Extensions that PHP reports as present without
+// a shared object are compiled in.
+func classifyExtensions(requirements composer.PlatformRequirements, available map[string]bool) []extension {
+	var extensions []extension
+	for _, requirement := range requirements.Requirements {
+		if !requirement.IsExtension() {
+			continue
+		}
+
+		ext := extension{name: requirement.Extension(), module: requirement.Extension(), requirement: requirement}
+		if module, ok := extensionModules[ext.name]; ok {
+			ext.module = module
+		}
+
+		switch {
+		case available[ext.module]:
+			ext.availability = ExtensionLoadable
+		case requirement.Status == composer.PlatformStatusMissing:
+			ext.availability = ExtensionUnavailable
+		default:
+			ext.availability = ExtensionBuiltIn
+		}
+
+		extensions = append(extensions, ext)
+	}
+
+	return extensions
+}
+
+// sharedObjects lists the extensions in the extension directory by module name
+func sharedObjects(dir string) (map[string]bool, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".so") {
+			modules[strings.TrimSuffix(file.Name(), ".so")] = true
+		}
+	}
+
+	return modules, nil
+}
+
+func unavailableExtensionsError(unavailable []extension, dir string, available map[string]bool) error {
+	var lines []string
+	for _, ext := range unavailable {
+		var requirers []string
+		for _, link := range ext.requirement.RequiredBy {
+			requirers = append(requirers, link.Package)
+		}
+
+		line := fmt.Sprintf("  %s", ext.name)
+		if len(requirers) > 0 {
+			line += fmt.Sprintf(" (required by %s)", strings.Join(requirers, ", "))
+		}
+		lines = append(lines, line)
+
+		if similar := similarModules(ext.module, available); len(similar) > 0 {
+			lines = append(lines, fmt.Sprintf("    did you mean %s?", strings.Join(similar, " or ")))
+		}
+		if polyfill, ok := polyfills[ext.name]; ok {
+			lines = append(lines, fmt.Sprintf("    require %s, which implements it in PHP", polyfill))
+		}
+	}
+
+	return fmt.Errorf("required PHP extensions are unavailable in %s:\n%s\n"+
+		"Use a PHP version that ships these extensions, or move requirements that are only needed for development to require-dev",
+		dir, strings.Join(lines, "\n"))
+}
+
+// similarModules returns available modules whose names are within two edits of the module, or one edit for short names
+func similarModules(module string, available map[string]bool) []string {
+	maxDistance := 2
+	if len(module) < 4 {
+		maxDistance = 1
+	}
+
+	var similar []string
+	for candidate := range available {
+		if distance(module, candidate) <= maxDistance {
+			similar = append(similar, candidate)
+		}
+	}
+	sort.Strings(similar)
+
+	return similar
+}
+
+// distance is the Levenshtein distance between two names
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+func minimum(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package packages
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitExtensions(t *testing.T) {
+	spec.Run(t, "Extensions", testExtensions, spec.Report(report.Terminal{}))
+}
+
+func testExtensions(t *testing.T, when spec.G, it spec.S) {
+	var (
+		contributor  Contributor
+		info         *bytes.Buffer
+		extensionDir string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory := test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+
+		var err error
+		contributor, _, err = NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		info = &bytes.Buffer{}
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+
+		phpHome := test.ScratchDir(t, "php")
+		extensionDir = filepath.Join(phpHome, "lib", "php", "extensions", "no-debug-non-zts-20190902")
+		for _, module := range []string{"pdo_mysql", "gd", "opcache"} {
+			test.WriteFile(t, filepath.Join(extensionDir, module+".so"), "")
+		}
+		Expect(os.Setenv("PHP_HOME", phpHome)).To(Succeed())
+		Expect(os.Setenv("PHP_API", "20190902")).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("PHP_HOME")).To(Succeed())
+		Expect(os.Unsetenv("PHP_API")).To(Succeed())
+		Expect(os.Unsetenv("PHP_EXTENSION_DIR")).To(Succeed())
+	})
+
+	it("enables the missing extensions that can be loaded", func() {
+		extensions, err := contributor.loadableExtensions(composer.ParsePlatformReqs(`ext-gd            n/a     __root__ requires ext-gd (*)            missing
+ext-json          7.4.3                                           success
+ext-zend-opcache  n/a     __root__ requires ext-zend-opcache (*)  missing
+php               7.4.3                                           success
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extensions).To(Equal([]string{"gd", "opcache"}))
+		Expect(info.String()).To(ContainSubstring("gd: loadable, enabling gd.so"))
+		Expect(info.String()).To(ContainSubstring("json: built-in"))
+	})
+
+	it("fails with suggestions when extensions are unavailable", func() {
+		_, err := contributor.loadableExtensions(composer.ParsePlatformReqs(`ext-mbstring   n/a  symfony/string requires ext-mbstring (*)  missing
+ext-pdo_mysqli n/a  __root__ requires ext-pdo_mysqli (*)       missing
+`))
+		Expect(err).To(MatchError(ContainSubstring("required PHP extensions are unavailable in " + extensionDir)))
+		Expect(err).To(MatchError(ContainSubstring("mbstring (required by symfony/string)")))
+		Expect(err).To(MatchError(ContainSubstring("require symfony/polyfill-mbstring, which implements it in PHP")))
+		Expect(err).To(MatchError(ContainSubstring("pdo_mysqli (required by __root__)\n    did you mean pdo_mysql?")))
+	})
+
+	it("enables every missing extension when the extension directory is unknown", func() {
+		Expect(os.Setenv("PHP_EXTENSION_DIR", filepath.Join(extensionDir, "missing"))).To(Succeed())
+
+		extensions, err := contributor.loadableExtensions(composer.ParsePlatformReqs(`ext-foo  n/a  __root__ requires ext-foo (*)  missing`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extensions).To(Equal([]string{"foo"}))
+	})
+
+	it("loads zend extensions with zend_extension", func() {
+		Expect(contributor.enablePHPExtensions([]string{"opcache", "gd"})).To(Succeed())
+
+		contents, err := ioutil.ReadFile(filepath.Join(contributor.app.Root, ".php.ini.d", "composer-extensions.ini"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("zend_extension = opcache.so\nextension = gd.so\n"))
+	})
+}
@@ -1,91 +1,95 @@
 package packages
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
-)
 
-const (
-	githubTimeout = 5
-	githubURL = "https://api.github.com/rate_limit"
+	"github.com/paketo-buildpacks/php-composer/composer"
 )
 
-type Github struct {
-	Token string
-	body  []byte
-}
-
-func NewDefaultGithub(token string) (Github, error){
-	return NewGithub(token, githubURL)
+// GitHub checks tokens for github.com or a GitHub Enterprise server
+type GitHub struct {
+	host   string
+	apiURL string
 }
 
-func NewGithub(token, url string) (Github, error) {
-	result := Github{
-		Token: token,
+// NewGitHub creates a checker for the GitHub at baseURL. The API of github.com lives on api.github.com, while GitHub
+// Enterprise serves it under /api/v3.
+func NewGitHub(baseURL string) GitHub {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" || parsed.Host == "github.com" {
+		return GitHub{host: "github.com", apiURL: "https://api.github.com"}
 	}
 
-	err := result.makeRequest(url)
-	if err != nil {
-		return result, err
-	}
-
-	return result, nil
+	return GitHub{host: parsed.Host, apiURL: baseURL + "/api/v3"}
 }
 
-func (g *Github) makeRequest(url string) error {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
+func (g GitHub) Name() string {
+	if g.enterprise() {
+		return "GitHub Enterprise"
 	}
+	return "GitHub"
+}
 
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", g.Token))
-	t := &http.Transport{Proxy: http.ProxyFromEnvironment}
+func (g GitHub) Host() string {
+	return g.host
+}
 
-	client := http.Client{Transport: t, Timeout: time.Second * githubTimeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// Check asks the rate limit API, which does not count against the quota, about the token
+func (g GitHub) Check(token string) (TokenStatus, error) {
+	header := http.Header{}
+	header.Set("Authorization", fmt.Sprintf("token %s", token))
+	header.Set("Accept", "application/vnd.github.v3+json")
 
-	g.body, err = ioutil.ReadAll(resp.Body)
+	resp, body, err := vcsRequest(g.apiURL+"/rate_limit", header)
 	if err != nil {
-		return err
+		return TokenStatus{}, err
+	}
+	if err := checkResponse(resp, body); err != nil {
+		return TokenStatus{}, err
 	}
 
-	return nil
-}
-
-func (g *Github) checkRateLimit() (bool, error) {
-	type GithubRateLimitResponse struct {
-		Resources struct {
+	var rateLimit struct {
+		Resources *struct {
 			Core struct {
-				Limit     int `json:"limit"`
-				Remaining int `json:"remaining"`
-				Reset     int `json:"reset"`
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
 			} `json:"core"`
 		} `json:"resources"`
 	}
-	rateLimitResp := GithubRateLimitResponse{}
+	if err := decodeJSON(body, &rateLimit); err != nil {
+		return TokenStatus{}, err
+	}
 
-	if err := json.Unmarshal(g.body, &rateLimitResp); err != nil {
-		return false, err
+	// a rejected token gets {"message": "Bad credentials"} instead of the rate limits
+	if rateLimit.Resources == nil {
+		return TokenStatus{}, nil
 	}
 
-	return rateLimitResp.Resources.Core.Remaining > 0, nil
+	core := rateLimit.Resources.Core
+	return TokenStatus{
+		Valid:     true,
+		Scopes:    splitScopes(resp.Header.Get("X-OAuth-Scopes")),
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Reset:     time.Unix(core.Reset, 0).UTC(),
+	}, nil
 }
 
-func (g *Github) validateToken() (bool, error) {
-	respMap := map[string]interface{}{}
-
-	if err := json.Unmarshal(g.body, &respMap); err != nil {
-		return false, err
+// Configure sets github-oauth for the host and, for GitHub Enterprise, adds the host to github-domains
+func (g GitHub) Configure(c composer.Composer, token string) error {
+	if g.enterprise() {
+		if err := c.ConfigList("github-domains", []string{"github.com", g.host}, true); err != nil {
+			return err
+		}
 	}
 
-	_, ok := respMap["resources"]
+	return c.Config("github-oauth."+g.host, token, true)
+}
 
-	return ok, nil
+func (g GitHub) enterprise() bool {
+	return g.host != "github.com"
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/sclevine/spec"
@@ -16,122 +17,110 @@ func TestUnitGithub(t *testing.T) {
 }
 
 func testGithub(t *testing.T, when spec.G, it spec.S) {
+	var (
+		response string
+		status   int
+		request  *http.Request
+		ts       *httptest.Server
+	)
+
 	it.Before(func() {
 		RegisterTestingT(t)
+		status = http.StatusOK
+
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+			w.WriteHeader(status)
+			fmt.Fprintln(w, response)
+		}))
+	})
+
+	it.After(func() {
+		ts.Close()
 	})
 
 	when("a github oauth token is supplied", func() {
-		it("validates the github token successfully when the token is correct", func(){
-			response := `{
+		it("validates the github token successfully when the token is correct", func() {
+			response = `{
 				"resources": {
 					"core": {
 						"limit": 5000,
-						"remaining": 5000,
+						"remaining": 4990,
 						"reset": 1560873755
 					},
 					"search": {
 						"limit": 30,
 						"remaining": 30,
 						"reset": 1560870215
-					},
-					"graphql": {
-						"limit": 5000,
-						"remaining": 5000,
-						"reset": 1560873755
-					},
-					"integration_manifest": {
-						"limit": 5000,
-						"remaining": 5000,
-						"reset": 1560873755
 					}
 				},
 				"rate": {
 					"limit": 5000,
-					"remaining": 5000,
+					"remaining": 4990,
 					"reset": 1560873755
 				}
 			}`
 
-			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				fmt.Fprintln(w, response)
+			github := NewGitHub(ts.URL)
+			tokenStatus, err := github.Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus).To(Equal(TokenStatus{
+				Valid:     true,
+				Scopes:    []string{"repo", "read:org"},
+				Limit:     5000,
+				Remaining: 4990,
+				Reset:     time.Unix(1560873755, 0).UTC(),
 			}))
-			defer ts.Close()
+			Expect(tokenStatus.RateLimited()).To(BeFalse())
 
-			github, err := NewGithub("FAKE", ts.URL)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(request.URL.Path).To(Equal("/api/v3/rate_limit"))
+			Expect(request.Header.Get("Authorization")).To(Equal("token FAKE"))
+		})
 
-			validation, err := github.validateToken()
-			Expect(err).NotTo(HaveOccurred())
-			Expect(validation).To(BeTrue())
+		it("validates the github token successfully when the token is correct and reports when the rate limit is met", func() {
+			response = `{"resources": {"core": {"limit": 5000, "remaining": 0, "reset": 1560873755}}}`
 
-			limitOk, err := github.checkRateLimit()
+			tokenStatus, err := NewGitHub(ts.URL).Check("FAKE")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(limitOk).To(BeTrue())
+			Expect(tokenStatus.Valid).To(BeTrue())
+			Expect(tokenStatus.RateLimited()).To(BeTrue())
 		})
 
-		it("validates the github token successfully when the token is correct and returns false if rate limit is met", func(){
-			response := `{
-				"resources": {
-					"core": {
-						"limit": 5000,
-						"remaining": 0,
-						"reset": 1560873755
-					},
-					"search": {
-						"limit": 30,
-						"remaining": 30,
-						"reset": 1560870215
-					},
-					"graphql": {
-						"limit": 5000,
-						"remaining": 5000,
-						"reset": 1560873755
-					},
-					"integration_manifest": {
-						"limit": 5000,
-						"remaining": 5000,
-						"reset": 1560873755
-					}
-				},
-				"rate": {
-					"limit": 5000,
-					"remaining": 0,
-					"reset": 1560873755
-				}
+		it("validates the github token successfully when the token is NOT correct", func() {
+			status = http.StatusUnauthorized
+			response = `{
+				"message": "Bad credentials",
+				"documentation_url": "https://developer.github.com/v3"
 			}`
 
-			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				fmt.Fprintln(w, response)
-			}))
-			defer ts.Close()
-
-			github, err := NewGithub("FAKE", ts.URL)
+			tokenStatus, err := NewGitHub(ts.URL).Check("FAKE")
 			Expect(err).NotTo(HaveOccurred())
-
-			validation, err := github.validateToken()
-			Expect(err).NotTo(HaveOccurred())
-			Expect(validation).To(BeTrue())
-
-			limitOk, err := github.checkRateLimit()
-			Expect(err).NotTo(HaveOccurred())
-			Expect(limitOk).To(BeFalse())
+			Expect(tokenStatus.Valid).To(BeFalse())
 		})
 
-		it("validates the github token successfully when the token is NOT correct", func(){
-			response := `{
-				"message": "Bad credentials",
-				"documentation_url": "https://developer.github.com/v3"
-			}`
+		it("returns an error when the API fails", func() {
+			status = http.StatusBadGateway
+			response = "upstream unavailable"
 
-			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				fmt.Fprintln(w, response)
-			}))
-			defer ts.Close()
+			_, err := NewGitHub(ts.URL).Check("FAKE")
+			Expect(err).To(MatchError("unexpected response 502 Bad Gateway: upstream unavailable"))
+		})
+	})
 
-			github, err := NewGithub("FAKE", ts.URL)
-			Expect(err).NotTo(HaveOccurred())
+	when("choosing the API", func() {
+		it("uses api.github.com for github.com", func() {
+			github := NewGitHub("https://github.com")
+			Expect(github.Name()).To(Equal("GitHub"))
+			Expect(github.Host()).To(Equal("github.com"))
+			Expect(github.apiURL).To(Equal("https://api.github.com"))
+		})
 
-			Expect(github.validateToken()).To(BeFalse())
+		it("uses /api/v3 for GitHub Enterprise", func() {
+			github := NewGitHub("https://github.example.com")
+			Expect(github.Name()).To(Equal("GitHub Enterprise"))
+			Expect(github.Host()).To(Equal("github.example.com"))
+			Expect(github.apiURL).To(Equal("https://github.example.com/api/v3"))
 		})
 	})
 }
@@ -0,0 +1,87 @@
+package packages
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+// GitLab checks tokens for gitlab.com or a self-managed GitLab
+type GitLab struct {
+	host    string
+	baseURL string
+}
+
+// NewGitLab creates a checker for the GitLab at baseURL
+func NewGitLab(baseURL string) GitLab {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		return GitLab{host: "gitlab.com", baseURL: composer.DefaultGitLabURL}
+	}
+
+	return GitLab{host: parsed.Host, baseURL: baseURL}
+}
+
+func (g GitLab) Name() string {
+	return "GitLab"
+}
+
+func (g GitLab) Host() string {
+	return g.host
+}
+
+// Check asks GitLab about the personal access token itself, falling back to the current user on GitLab versions that
+// cannot describe tokens
+func (g GitLab) Check(token string) (TokenStatus, error) {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+
+	resp, body, err := vcsRequest(g.baseURL+"/api/v4/personal_access_tokens/self", header)
+	if err != nil {
+		return TokenStatus{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		if resp, body, err = vcsRequest(g.baseURL+"/api/v4/user", header); err != nil {
+			return TokenStatus{}, err
+		}
+	}
+
+	if err := checkResponse(resp, body); err != nil {
+		return TokenStatus{}, err
+	}
+
+	status := TokenStatus{
+		Limit:     headerInt(resp.Header, "RateLimit-Limit"),
+		Remaining: headerInt(resp.Header, "RateLimit-Remaining"),
+		Reset:     time.Unix(int64(headerInt(resp.Header, "RateLimit-Reset")), 0).UTC(),
+	}
+	if resp.StatusCode >= 300 {
+		return status, nil
+	}
+
+	var self struct {
+		Scopes []string `json:"scopes"`
+		Active *bool    `json:"active"`
+	}
+	if err := decodeJSON(body, &self); err != nil {
+		return TokenStatus{}, err
+	}
+
+	status.Valid = self.Active == nil || *self.Active
+	status.Scopes = self.Scopes
+	return status, nil
+}
+
+// Configure sets gitlab-token for the host and, for a self-managed GitLab, adds the host to gitlab-domains
+func (g GitLab) Configure(c composer.Composer, token string) error {
+	if g.host != "gitlab.com" {
+		if err := c.ConfigList("gitlab-domains", []string{"gitlab.com", g.host}, true); err != nil {
+			return err
+		}
+	}
+
+	return c.Config("gitlab-token."+g.host, token, true)
+}
@@ -0,0 +1,122 @@
+package packages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// packagesKey holds everything that changes what Composer installs into the packages layer. Its hash is stored in
+// the layer metadata, so the layer is reused until one of these inputs changes.
+type packagesKey struct {
+	Packages        []string `json:"packages,omitempty"`
+	ContentHash     string   `json:"content_hash,omitempty"`
+	InstallOptions  []string `json:"install_options"`
+	VendorDirectory string   `json:"vendor_directory"`
+	Autoloader      string   `json:"autoloader"`
+	PHPVersion      string   `json:"php_version"`
+	PHPAPI          string   `json:"php_api"`
+	Composer        string   `json:"composer"`
+}
+
+// newPackagesKey builds the key from the resolved packages in composer.lock or, for apps without a lock file, from
+// the content-hash of composer.json
+func newPackagesKey(composerJSONPath, composerPharPath string, cfg composer.Config) (packagesKey, error) {
+	key := packagesKey{
+		InstallOptions:  cfg.InstallOptions,
+		VendorDirectory: cfg.VendorDirectory,
+		Autoloader:      cfg.Autoloader,
+		PHPVersion:      os.Getenv("PHP_VERSION"),
+		PHPAPI:          os.Getenv("PHP_API"),
+	}
+
+	lockPath := filepath.Join(filepath.Dir(composerJSONPath), composer.ComposerLock)
+	if exists, err := helper.FileExists(lockPath); err != nil {
+		return packagesKey{}, err
+	} else if exists {
+		lock, err := manifest.ReadLock(lockPath)
+		if err != nil {
+			return packagesKey{}, err
+		}
+
+		key.Packages = resolvedPackages(lock, !composer.Contains(cfg.InstallOptions, "--no-dev"))
+	} else {
+		buf, err := ioutil.ReadFile(composerJSONPath)
+		if err != nil {
+			return packagesKey{}, err
+		}
+
+		if key.ContentHash, err = manifest.ContentHash(buf); err != nil {
+			return packagesKey{}, fmt.Errorf("unable to compute the content-hash of %s: %w", composerJSONPath, err)
+		}
+	}
+
+	var err error
+	if key.Composer, err = fileDigest(composerPharPath); err != nil {
+		return packagesKey{}, err
+	}
+
+	return key, nil
+}
+
+// hash returns the SHA-256 of the key
+func (k packagesKey) hash() (string, error) {
+	buf, err := json.Marshal(k)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(buf)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// resolvedPackages lists the locked packages as "name@version#reference", ignoring everything else in the lock file
+func resolvedPackages(lock manifest.Lock, dev bool) []string {
+	packages := lock.Packages
+	if dev {
+		packages = lock.AllPackages()
+	}
+
+	var resolved []string
+	for _, pkg := range packages {
+		reference := ""
+		if pkg.Dist != nil {
+			reference = pkg.Dist.Reference
+		} else if pkg.Source != nil {
+			reference = pkg.Source.Reference
+		}
+
+		resolved = append(resolved, fmt.Sprintf("%s@%s#%s", pkg.Name, pkg.Version, reference))
+	}
+	sort.Strings(resolved)
+
+	return resolved
+}
+
+// fileDigest returns the SHA-256 of a file, or an empty string if it does not exist
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,76 @@
+package packages
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/licenses"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// licensePolicy reads the policy file from the app and applies the allow and deny lists from the environment, which
+// replace the lists of the file
+func (c Contributor) licensePolicy() (licenses.Policy, error) {
+	policy, err := licenses.ReadPolicy(filepath.Join(c.app.Root, c.composerConfig.LicensePolicy))
+	if err != nil {
+		return licenses.Policy{}, err
+	}
+
+	if c.composerConfig.LicenseAllow != nil {
+		policy.Allow = c.composerConfig.LicenseAllow
+	}
+	if c.composerConfig.LicenseDeny != nil {
+		policy.Deny = c.composerConfig.LicenseDeny
+	}
+
+	return policy, nil
+}
+
+// enforceLicensePolicy fails when a package declares no license the policy permits. The packages are those locked in
+// composer.lock or, for apps without a lock file, those in installed.json.
+func (c Contributor) enforceLicensePolicy() error {
+	policy, err := c.licensePolicy()
+	if err != nil {
+		return err
+	} else if policy.Empty() {
+		return nil
+	}
+
+	logger := c.composer.Logger
+	logger.Header("License policy")
+
+	packages, source, err := c.installedPackages()
+	if err != nil {
+		return err
+	} else if source == "" {
+		logger.BodyWarning("Unable to check the licenses of the packages, neither composer.lock nor installed.json was found")
+		return nil
+	}
+
+	root, err := manifest.ReadManifest(c.composerJSONPath)
+	if err != nil {
+		return err
+	}
+
+	paths := manifest.DependencyPaths(root, packages, !composer.Contains(c.composerConfig.InstallOptions, "--no-dev"))
+	violations := policy.Check(packages, paths)
+	if len(violations) == 0 {
+		logger.Body("All %d packages declare a permitted license", len(packages))
+		return nil
+	}
+
+	var details []string
+	for _, violation := range violations {
+		details = append(details, "  "+violation.String())
+	}
+
+	return fmt.Errorf("%d packages declare a license that is not permitted by the license policy:\n%s", len(violations), strings.Join(details, "\n"))
+}
+
+// hasLock reports whether the project has a composer.lock, whose packages are known before they are installed
+func (c Contributor) hasLock() (bool, error) {
+	return helper.FileExists(filepath.Join(filepath.Dir(c.composerJSONPath), composer.ComposerLock))
+}
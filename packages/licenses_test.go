@@ -0,0 +1,80 @@
+package packages
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitLicenses(t *testing.T) {
+	spec.Run(t, "Licenses", testLicenses, spec.Report(report.Terminal{}))
+}
+
+func testLicenses(t *testing.T, when spec.G, it spec.S) {
+	var factory *test.BuildFactory
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), `{"require": {"acme/app": "^1.0"}}`)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerLock), `{"packages": [
+	{"name": "acme/app", "version": "1.0.0", "license": ["MIT"], "require": {"acme/gpl": "^2.0"}},
+	{"name": "acme/gpl", "version": "2.0.0", "license": ["GPL-3.0-only"]}
+]}`)
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv(composer.LicenseAllowEnv)).To(Succeed())
+		Expect(os.Unsetenv(composer.LicenseDenyEnv)).To(Succeed())
+		Expect(os.Unsetenv(composer.VendorDirectoryEnv)).To(Succeed())
+	})
+
+	newContributor := func() Contributor {
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+		return contributor
+	}
+
+	it("does nothing without a policy", func() {
+		Expect(newContributor().enforceLicensePolicy()).To(Succeed())
+	})
+
+	it("fails on packages with a denied license and lists the requirement path", func() {
+		Expect(os.Setenv(composer.LicenseDenyEnv, "GPL-3.0-only,AGPL-3.0-only")).To(Succeed())
+
+		err := newContributor().enforceLicensePolicy()
+		Expect(err).To(MatchError(ContainSubstring("1 packages declare a license that is not permitted")))
+		Expect(err).To(MatchError(ContainSubstring("acme/gpl 2.0.0 (GPL-3.0-only) required by composer.json > acme/app > acme/gpl")))
+	})
+
+	it("reads the policy file from the app", func() {
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.DefaultLicensePolicy), "allow: [MIT]\n")
+		Expect(newContributor().enforceLicensePolicy()).To(MatchError(ContainSubstring("acme/gpl")))
+	})
+
+	it("prefers the environment over the policy file", func() {
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.DefaultLicensePolicy), "allow: [MIT, GPL-3.0-only]\n")
+		Expect(os.Setenv(composer.LicenseAllowEnv, "MIT")).To(Succeed())
+		Expect(newContributor().enforceLicensePolicy()).To(MatchError(ContainSubstring("acme/gpl")))
+
+		Expect(os.Setenv(composer.LicenseAllowEnv, "MIT GPL-3.0-only")).To(Succeed())
+		Expect(newContributor().enforceLicensePolicy()).To(Succeed())
+	})
+
+	it("checks the locked packages before installing them", func() {
+		Expect(os.Setenv(composer.LicenseDenyEnv, "GPL-3.0-only")).To(Succeed())
+		runner := &recordingRunner{}
+		contributor := newContributor()
+		contributor.composer.Runner = runner
+
+		Expect(contributor.contributeProject()).To(MatchError(ContainSubstring("acme/gpl")))
+		Expect(runner.commands).To(BeEmpty())
+	})
+}
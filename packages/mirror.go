@@ -0,0 +1,184 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/paketo-buildpacks/php-composer/bindings"
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+// PackagistRepository is the name of Composer's default repository, which the Packagist mirror replaces
+const PackagistRepository = "packagist.org"
+
+// CABindingTypes are the binding types that provide additional CA certificates, e.g. of a TLS inspection proxy
+var CABindingTypes = []string{"ca-certificates"}
+
+// systemCABundles are the locations of the system CA bundle, which Composer no longer uses once cafile is set
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/cert.pem",
+}
+
+// configureRepositories replaces Packagist with the mirror or disables it and installs the CA bundle from bindings in
+// the global Composer configuration. It lives in COMPOSER_HOME, which is never part of the launch image, and the
+// settings are removed again once the packages are installed.
+func (c Contributor) configureRepositories() error {
+	logger := c.composer.Logger
+
+	if mirror := c.composerConfig.Mirror; mirror != "" {
+		repository, err := json.Marshal(map[string]string{"type": "composer", "url": mirror})
+		if err != nil {
+			return err
+		}
+
+		logger.Body("Using the Packagist mirror %s", composer.RedactURL(mirror))
+		if err := c.composer.Config("repositories."+PackagistRepository, string(repository), true); err != nil {
+			return err
+		}
+	} else if c.composerConfig.DisablePackagist {
+		logger.Body("Disabling the %s repository", PackagistRepository)
+		if err := c.composer.Config("repositories."+PackagistRepository, "false", true); err != nil {
+			return err
+		}
+	}
+
+	for _, env := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if proxy := os.Getenv(env); proxy != "" {
+			logger.Body("Using the proxy %s from %s", composer.RedactURL(proxy), env)
+			break
+		}
+	}
+
+	bundle, err := c.caBundle()
+	if err != nil || bundle == "" {
+		return err
+	}
+
+	return c.composer.Config("cafile", bundle, true)
+}
+
+// caBundle writes the certificates from `ca-certificates` bindings, following the system CA bundle, to a bundle in
+// COMPOSER_HOME and returns its path. The path is empty when there are no such bindings.
+func (c Contributor) caBundle() (string, error) {
+	all, err := bindings.Resolve()
+	if err != nil {
+		return "", err
+	}
+
+	caBindings := bindings.OfType(all, CABindingTypes...)
+	if len(caBindings) == 0 {
+		return "", nil
+	}
+
+	buf := bytes.Buffer{}
+	if system, err := systemCABundle(); err != nil {
+		return "", err
+	} else if system != "" {
+		contents, err := ioutil.ReadFile(system)
+		if err != nil {
+			return "", err
+		}
+		buf.Write(bytes.TrimSpace(contents))
+		buf.WriteString("\n")
+	}
+
+	for _, binding := range caBindings {
+		for _, key := range binding.Keys() {
+			contents, err := ioutil.ReadFile(binding.Entries[key])
+			if err != nil {
+				return "", err
+			}
+
+			if !bytes.Contains(contents, []byte("-----BEGIN CERTIFICATE-----")) {
+				continue
+			}
+
+			c.composer.Logger.Body("Adding CA certificates %s from binding %s", key, binding.Name)
+			buf.Write(bytes.TrimSpace(contents))
+			buf.WriteString("\n")
+		}
+	}
+
+	path := c.caBundlePath()
+	return path, helper.WriteFile(path, 0644, "%s", buf.String())
+}
+
+func (c Contributor) caBundlePath() string {
+	return filepath.Join(os.Getenv("COMPOSER_HOME"), "ca-bundle.crt")
+}
+
+// removeRepositories removes the settings written by configureRepositories from the global Composer configuration,
+// which holds other settings such as the VCS domains as well, and the CA bundle
+func (c Contributor) removeRepositories() error {
+	path := filepath.Join(os.Getenv("COMPOSER_HOME"), "config.json")
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	} else if err == nil {
+		global := map[string]interface{}{}
+		if err := json.Unmarshal(contents, &global); err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+
+		if c.composerConfig.Mirror != "" || c.composerConfig.DisablePackagist {
+			removeKey(global, "repositories", PackagistRepository)
+		}
+
+		if settings, ok := global["config"].(map[string]interface{}); ok && settings["cafile"] == c.caBundlePath() {
+			removeKey(global, "config", "cafile")
+		}
+
+		contents, err := json.MarshalIndent(global, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, append(contents, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(c.caBundlePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeKey removes key from the object section of the configuration, and the section once it is empty
+func removeKey(configuration map[string]interface{}, section string, key string) {
+	values, ok := configuration[section].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	delete(values, key)
+	if len(values) == 0 {
+		delete(configuration, section)
+	}
+}
+
+// systemCABundle returns the CA bundle from SSL_CERT_FILE or the first of the usual locations that exists
+func systemCABundle() (string, error) {
+	candidates := systemCABundles
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		candidates = append([]string{file}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		if exists, err := helper.FileExists(candidate); err != nil {
+			return "", err
+		} else if exists {
+			return candidate, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,129 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// pathRepositories resolves the directories matched by the path repositories of composer.json and verifies that
+// they live inside the app root, as anything outside of it is not part of the launch image
+func (c Contributor) pathRepositories() ([]string, error) {
+	root, err := manifest.ReadManifest(c.composerJSONPath)
+	if err != nil {
+		return nil, err
+	}
+
+	appRoot, err := filepath.EvalSymlinks(c.app.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, repository := range root.Repositories.OfType("path") {
+		pattern := repository.URL
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(c.composerJSONPath), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path repository %s: %w", repository.URL, err)
+		} else if len(matches) == 0 {
+			c.composer.Logger.BodyWarning("Path repository %s does not match any directory", repository.URL)
+		}
+
+		for _, match := range matches {
+			dir, err := filepath.EvalSymlinks(match)
+			if err != nil {
+				return nil, err
+			}
+
+			if !within(appRoot, dir) {
+				return nil, fmt.Errorf("path repository %s resolves to %s, which is outside of the app root and will not be part of the launch image", repository.URL, dir)
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs, nil
+}
+
+// relinkPathPackages points the packages Composer symlinked from path repositories at their absolute location in the
+// app root. Composer links them relative to the vendor directory in the packages layer, which does not resolve once
+// the vendor directory is linked back into the app. Links to anything outside of the app root fail the build, as
+// pathRepositories does.
+func (c Contributor) relinkPathPackages(vendorDir string) error {
+	appRoot, err := filepath.EvalSymlinks(c.app.Root)
+	if err != nil {
+		return err
+	}
+
+	vendorDir, err = filepath.EvalSymlinks(vendorDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	links, err := filepath.Glob(filepath.Join(vendorDir, "*", "*"))
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		info, err := os.Lstat(link)
+		if err != nil {
+			return err
+		} else if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		target, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			return fmt.Errorf("package %s links to a path that does not exist: %w", packageName(vendorDir, link), err)
+		}
+
+		if within(vendorDir, target) {
+			continue
+		}
+
+		if !within(appRoot, target) {
+			return fmt.Errorf("package %s links to %s, which is outside of the app root and will not be part of the launch image", packageName(vendorDir, link), target)
+		}
+
+		rel, err := filepath.Rel(appRoot, target)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+
+		c.composer.Logger.Body("Linking %s to %s in the app", packageName(vendorDir, link), rel)
+		if err := os.Symlink(filepath.Join(c.app.Root, rel), link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// packageName returns the name of the package installed at path in the vendor directory
+func packageName(vendorDir, path string) string {
+	rel, err := filepath.Rel(vendorDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// within reports whether path is dir or one of its descendants
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,118 @@
+package packages
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitPathRepositories(t *testing.T) {
+	spec.Run(t, "PathRepositories", testPathRepositories, spec.Report(report.Terminal{}))
+}
+
+func testPathRepositories(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory     *test.BuildFactory
+		contributor Contributor
+		info        *bytes.Buffer
+	)
+
+	newContributor := func(composerJSON string) {
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), composerJSON)
+
+		var err error
+		contributor, _, err = NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		info = &bytes.Buffer{}
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "packages", "acme", "composer.json"), "{}")
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, "packages", "tools", "composer.json"), "{}")
+	})
+
+	when("checking path repositories", func() {
+		it("resolves the directories matched by each repository", func() {
+			newContributor(`{"repositories": [{"type": "path", "url": "packages/*"}, {"type": "composer", "url": "https://satis.example.com"}]}`)
+
+			dirs, err := contributor.pathRepositories()
+			Expect(err).NotTo(HaveOccurred())
+
+			appRoot, err := filepath.EvalSymlinks(factory.Build.Application.Root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirs).To(Equal([]string{filepath.Join(appRoot, "packages", "acme"), filepath.Join(appRoot, "packages", "tools")}))
+		})
+
+		it("fails for repositories outside of the app root", func() {
+			outside := test.ScratchDir(t, "outside")
+			newContributor(`{"repositories": [{"type": "path", "url": "` + outside + `"}]}`)
+
+			_, err := contributor.pathRepositories()
+			Expect(err).To(MatchError(ContainSubstring("path repository " + outside + " resolves to")))
+			Expect(err).To(MatchError(ContainSubstring("which is outside of the app root")))
+		})
+
+		it("warns about repositories that match nothing", func() {
+			newContributor(`{"repositories": [{"type": "path", "url": "../lib"}]}`)
+
+			dirs, err := contributor.pathRepositories()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirs).To(BeEmpty())
+			Expect(info.String()).To(ContainSubstring("Path repository ../lib does not match any directory"))
+		})
+	})
+
+	when("relinking path packages", func() {
+		var vendorDir string
+
+		it.Before(func() {
+			newContributor(`{"repositories": [{"type": "path", "url": "packages/*"}]}`)
+			vendorDir = filepath.Join(contributor.composerPackagesLayer.Root, "vendor")
+			test.WriteFile(t, filepath.Join(vendorDir, "monolog", "monolog", "composer.json"), "{}")
+			Expect(os.MkdirAll(filepath.Join(vendorDir, "acme"), 0755)).To(Succeed())
+		})
+
+		it("points links into the app at the absolute path", func() {
+			link := filepath.Join(vendorDir, "acme", "acme")
+			target, err := filepath.Rel(filepath.Dir(link), filepath.Join(factory.Build.Application.Root, "packages", "acme"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Symlink(target, link)).To(Succeed())
+			Expect(os.Symlink(filepath.Join("..", "monolog", "monolog"), filepath.Join(vendorDir, "acme", "internal"))).To(Succeed())
+
+			Expect(contributor.relinkPathPackages(vendorDir)).To(Succeed())
+
+			Expect(os.Readlink(link)).To(Equal(filepath.Join(factory.Build.Application.Root, "packages", "acme")))
+			Expect(os.Readlink(filepath.Join(vendorDir, "acme", "internal"))).To(Equal(filepath.Join("..", "monolog", "monolog")))
+			Expect(info.String()).To(ContainSubstring("Linking acme/acme to packages/acme in the app"))
+		})
+
+		it("fails for packages linked from outside of the app root", func() {
+			outside := test.ScratchDir(t, "outside")
+			test.WriteFile(t, filepath.Join(outside, "composer.json"), "{}")
+			Expect(os.Symlink(outside, filepath.Join(vendorDir, "acme", "outside"))).To(Succeed())
+
+			Expect(contributor.relinkPathPackages(vendorDir)).To(MatchError(ContainSubstring("package acme/outside links to")))
+			Expect(filepath.Join(vendorDir, "acme", "outside")).To(BeADirectory())
+		})
+
+		it("fails for dangling links", func() {
+			Expect(os.Symlink(filepath.Join(factory.Build.Application.Root, "missing"), filepath.Join(vendorDir, "acme", "missing"))).To(Succeed())
+
+			Expect(contributor.relinkPathPackages(vendorDir)).To(MatchError(ContainSubstring("package acme/missing links to a path that does not exist")))
+		})
+	})
+}
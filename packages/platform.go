@@ -0,0 +1,44 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+// logPlatformRequirements reports every platform requirement with the constraints placed on it, the provided version
+// and its status. Missing extensions are enabled afterwards, anything else that is not satisfied is a warning.
+func (c Contributor) logPlatformRequirements(requirements composer.PlatformRequirements) {
+	if len(requirements.Requirements) == 0 {
+		return
+	}
+
+	logger := c.composer.Logger
+	logger.Header("Platform requirements")
+
+	for _, requirement := range requirements.Requirements {
+		provided := requirement.Provided
+		if provided == "" {
+			provided = "n/a"
+		}
+		if requirement.ProvidedBy != "" {
+			provided += " provided by " + requirement.ProvidedBy
+		}
+
+		message := fmt.Sprintf("%s %s: %s", requirement.Name, provided, requirement.Status)
+		if len(requirement.RequiredBy) > 0 {
+			var links []string
+			for _, link := range requirement.RequiredBy {
+				links = append(links, fmt.Sprintf("%s requires %s", link.Package, link.Constraint))
+			}
+			message += fmt.Sprintf(" (%s)", strings.Join(links, ", "))
+		}
+
+		if requirement.Status == composer.PlatformStatusFailed || (requirement.Status == composer.PlatformStatusMissing && !requirement.IsExtension()) {
+			logger.BodyWarning("%s", message)
+		} else {
+			logger.Body("%s", message)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package packages
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitPlatform(t *testing.T) {
+	spec.Run(t, "Platform", testPlatform, spec.Report(report.Terminal{}))
+}
+
+func testPlatform(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("logs every platform requirement", func() {
+		factory := test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		info := &bytes.Buffer{}
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+
+		contributor.logPlatformRequirements(composer.ParsePlatformReqs(`ext-mbstring  1.23.1  success provided by symfony/polyfill-mbstring
+ext-pdo       n/a     doctrine/orm requires ext-pdo (*)  missing
+php           7.4.3   __root__ requires php (^8.0)       failed
+`))
+
+		Expect(info.String()).To(ContainSubstring("Platform requirements"))
+		Expect(info.String()).To(ContainSubstring("ext-mbstring 1.23.1 provided by symfony/polyfill-mbstring: success"))
+		Expect(info.String()).To(ContainSubstring("ext-pdo n/a: missing (doctrine/orm requires *)"))
+		Expect(info.String()).To(ContainSubstring("php 7.4.3: failed (__root__ requires ^8.0)"))
+	})
+}
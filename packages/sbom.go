@@ -0,0 +1,112 @@
+package packages
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry/libcfbuildpack/helper"
+	"github.com/cloudfoundry/libcfbuildpack/layers"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/manifest"
+	"github.com/paketo-buildpacks/php-composer/sbom"
+)
+
+// writeSBOM records the installed packages as CycloneDX and SPDX documents in the sbom directory of the packages layer.
+// Buildpack API 0.4 has no layer SBOM files, so the documents ship in the launch layer with the packages they describe
+// and are only rewritten when the layer is.
+func (c Contributor) writeSBOM(layer layers.Layer) error {
+	packages, source, err := c.installedPackages()
+	if err != nil {
+		return err
+	}
+	components := sbom.FromPackages(packages)
+
+	logger := c.composer.Logger
+	logger.Header("Software Bill of Materials")
+	if source == "" {
+		logger.BodyWarning("Unable to generate a Software Bill of Materials, neither %s nor installed.json was found", composer.ComposerLock)
+		return nil
+	}
+
+	cycloneDX, err := sbom.CycloneDX(components, c.buildpackVersion)
+	if err != nil {
+		return err
+	}
+
+	created, err := sourceDateEpoch()
+	if err != nil {
+		return err
+	}
+
+	spdx, err := sbom.SPDX(c.name, components, c.buildpackVersion, created)
+	if err != nil {
+		return err
+	}
+
+	cycloneDXPath := filepath.Join(layer.Root, "sbom", c.name+".cdx.json")
+	spdxPath := filepath.Join(layer.Root, "sbom", c.name+".spdx.json")
+
+	if err := helper.WriteFile(cycloneDXPath, 0644, "%s", cycloneDX); err != nil {
+		return err
+	}
+	if err := helper.WriteFile(spdxPath, 0644, "%s", spdx); err != nil {
+		return err
+	}
+
+	logger.Body("Recorded %d packages from %s", len(components), source)
+	if len(components) > 0 {
+		logger.Body("Licenses: %s", sbom.LicenseSummary(components))
+	}
+	logger.Body("Wrote %s and %s", filepath.Base(cycloneDXPath), filepath.Base(spdxPath))
+
+	return nil
+}
+
+// installedPackages reads the installed packages from composer.lock or, for apps without a lock file, from the
+// installed.json Composer wrote into the vendor directory. The source is empty when neither exists.
+func (c Contributor) installedPackages() ([]manifest.Package, string, error) {
+	lockPath := filepath.Join(filepath.Dir(c.composerJSONPath), composer.ComposerLock)
+	if exists, err := helper.FileExists(lockPath); err != nil {
+		return nil, "", err
+	} else if exists {
+		lock, err := manifest.ReadLock(lockPath)
+		if err != nil {
+			return nil, "", err
+		}
+
+		packages := lock.Packages
+		if !composer.Contains(c.composerConfig.InstallOptions, "--no-dev") {
+			packages = lock.AllPackages()
+		}
+		return packages, composer.ComposerLock, nil
+	}
+
+	installedPath := filepath.Join(c.composerPackagesLayer.Root, c.composerConfig.VendorDirectory, "composer", "installed.json")
+	if exists, err := helper.FileExists(installedPath); err != nil {
+		return nil, "", err
+	} else if exists {
+		installed, err := manifest.ReadInstalled(installedPath)
+		if err != nil {
+			return nil, "", err
+		}
+		return installed.Packages, filepath.Base(installedPath), nil
+	}
+
+	return nil, "", nil
+}
+
+// sourceDateEpoch returns the time given by SOURCE_DATE_EPOCH for reproducible builds, or the current time
+func sourceDateEpoch() (time.Time, error) {
+	epoch, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || epoch == "" {
+		return time.Now(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
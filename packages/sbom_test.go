@@ -0,0 +1,90 @@
+package packages
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitSBOM(t *testing.T) {
+	spec.Run(t, "SBOM", testSBOM, spec.Report(report.Terminal{}))
+}
+
+func testSBOM(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory *test.BuildFactory
+		info    *bytes.Buffer
+		sbomDir string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+		info = &bytes.Buffer{}
+		sbomDir = filepath.Join(factory.Build.Layers.Layer(composer.PackagesDependency).Root, "sbom")
+	})
+
+	newContributor := func() Contributor {
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+		return contributor
+	}
+
+	when("there is a lock file", func() {
+		it.Before(func() {
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerLock), `{
+	"packages": [{"name": "monolog/monolog", "version": "1.25.1", "license": ["MIT"], "dist": {"url": "https://example.com/monolog.zip", "shasum": "da39a3ee"}}],
+	"packages-dev": [{"name": "phpunit/phpunit", "version": "8.5.0", "license": ["BSD-3-Clause"]}]
+}`)
+		})
+
+		it("writes CycloneDX and SPDX files into the packages layer", func() {
+			contributor := newContributor()
+			Expect(contributor.writeSBOM(contributor.composerPackagesLayer)).To(Succeed())
+
+			cycloneDX := filepath.Join(sbomDir, composer.PackagesDependency+".cdx.json")
+			Expect(cycloneDX).To(BeARegularFile())
+			buf, err := ioutil.ReadFile(cycloneDX)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf)).To(ContainSubstring("pkg:composer/monolog/monolog@1.25.1"))
+			Expect(string(buf)).NotTo(ContainSubstring("phpunit"))
+
+			Expect(filepath.Join(sbomDir, composer.PackagesDependency+".spdx.json")).To(BeARegularFile())
+
+			Expect(info.String()).To(ContainSubstring("Recorded 1 packages from composer.lock"))
+			Expect(info.String()).To(ContainSubstring("Licenses: MIT (1)"))
+		})
+	})
+
+	when("there is only an installed.json", func() {
+		it("reads the installed packages", func() {
+			contributor := newContributor()
+			test.WriteFile(t, filepath.Join(contributor.composerPackagesLayer.Root, "vendor", "composer", "installed.json"), `[{"name": "psr/log", "version": "1.1.2"}]`)
+
+			Expect(contributor.writeSBOM(contributor.composerPackagesLayer)).To(Succeed())
+			Expect(info.String()).To(ContainSubstring("Recorded 1 packages from installed.json"))
+		})
+	})
+
+	when("nothing records the installed packages", func() {
+		it("warns", func() {
+			contributor := newContributor()
+			Expect(contributor.writeSBOM(contributor.composerPackagesLayer)).To(Succeed())
+			Expect(info.String()).To(ContainSubstring("Unable to generate a Software Bill of Materials"))
+			Expect(filepath.Join(sbomDir, composer.PackagesDependency+".cdx.json")).NotTo(BeAnExistingFile())
+		})
+	})
+}
@@ -0,0 +1,272 @@
+package packages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/runner"
+)
+
+// The script events Composer dispatches during `composer install`, in order. When the scripts policy restricts them,
+// the buildpack runs the allowed ones itself and times each event.
+const (
+	preInstallEvent       = "pre-install-cmd"
+	preAutoloadDumpEvent  = "pre-autoload-dump"
+	postAutoloadDumpEvent = "post-autoload-dump"
+	postInstallEvent      = "post-install-cmd"
+)
+
+// autoloadOptions maps the autoloader options of `composer install` to their `composer dump-autoload` equivalents
+var autoloadOptions = [][2]string{
+	{"-o", "--optimize"},
+	{"--optimize-autoloader", "--optimize"},
+	{"-a", "--classmap-authoritative"},
+	{"--classmap-authoritative", "--classmap-authoritative"},
+	{"--apcu-autoloader", "--apcu"},
+	{"--apcu-autoloader-prefix=", "--apcu-prefix="},
+}
+
+// installPackages runs `composer install`. When every script may run, Composer runs them itself, for all of its
+// events, and the events are followed in its output. Otherwise scripts are disabled for the install and the autoloader is dumped separately, with the permitted
+// install and autoload-dump events run around it, as Composer would.
+func (c Contributor) installPackages() error {
+	installOptions := append([]string{}, c.composerConfig.InstallOptions...)
+
+	if c.runsAllScripts() {
+		return c.installWithScripts(append(installOptions, c.autoloaderInstallOptions()...))
+	}
+
+	if err := c.runScripts(preInstallEvent); err != nil {
+		return err
+	}
+
+	if !composer.Contains(installOptions, "--no-scripts") {
+		installOptions = append(installOptions, "--no-scripts")
+	}
+
+	if composer.Contains(installOptions, "--no-autoloader") {
+		if err := c.composer.Install(installOptions...); err != nil {
+			return err
+		}
+	} else {
+		if err := c.composer.Install(append(installOptions, "--no-autoloader")...); err != nil {
+			return err
+		}
+
+		if err := c.runScripts(preAutoloadDumpEvent); err != nil {
+			return err
+		}
+
+		if err := c.composer.DumpAutoload(c.dumpAutoloadOptions()...); err != nil {
+			return err
+		}
+
+		if err := c.runScripts(postAutoloadDumpEvent); err != nil {
+			return err
+		}
+	}
+
+	return c.runScripts(postInstallEvent)
+}
+
+// verbosityOptions are the options with which Composer names the event of every script it runs
+var verbosityOptions = []string{"-v", "-vv", "-vvv", "--verbose"}
+
+// installWithScripts runs `composer install` with its scripts. Composer runs verbosely, so that it names the event of
+// every script it runs, and each event is logged with its duration and exit code.
+func (c Contributor) installWithScripts(options []string) error {
+	verbose := false
+	for _, option := range verbosityOptions {
+		verbose = verbose || composer.Contains(options, option)
+	}
+	if !verbose {
+		options = append(options, "-v")
+	}
+
+	scripts := &scriptLog{logger: c.composer.Logger}
+	install := c.composer
+	install.Output = scripts
+	return scripts.close(install.Install(options...))
+}
+
+// runsAllScripts reports whether the scripts policy lets every event run, which is what Composer does by default
+func (c Contributor) runsAllScripts() bool {
+	return len(c.composerConfig.Scripts) == 1 && c.composerConfig.Scripts[0] == composer.ScriptsAll &&
+		!composer.Contains(c.composerConfig.InstallOptions, "--no-scripts")
+}
+
+// autoloaderInstallOptions returns the `composer install` options for the autoloader mode that the install options do
+// not already select
+func (c Contributor) autoloaderInstallOptions() []string {
+	selected := c.installAutoloadOptions()
+
+	var options []string
+	for _, option := range c.composerConfig.AutoloaderOptions() {
+		if composer.Contains(selected, option) {
+			continue
+		}
+		for _, mapping := range autoloadOptions {
+			if mapping[1] == option && strings.HasPrefix(mapping[0], "--") {
+				options = append(options, mapping[0])
+				break
+			}
+		}
+	}
+	return options
+}
+
+// runScripts runs the scripts of an event if composer.json defines any and the scripts policy allows it
+func (c Contributor) runScripts(event string) error {
+	if len(c.scripts[event]) == 0 {
+		return nil
+	}
+
+	if !c.composerConfig.RunsScripts(event) {
+		c.composer.Logger.Body("Skipping %s scripts", event)
+		return nil
+	}
+
+	c.composer.Logger.Body("Running %s scripts", event)
+
+	start := time.Now()
+	err := c.composer.RunScript(event, c.devOption())
+	duration := time.Since(start).Round(time.Millisecond)
+
+	exitCode := scriptExitCode(err)
+	c.composer.Logger.Body("Finished %s scripts in %s with exit code %d", event, duration, exitCode)
+	if err != nil {
+		return fmt.Errorf("composer script %s failed with exit code %d: %w", event, exitCode, err)
+	}
+
+	return nil
+}
+
+// dumpAutoloadOptions combines the autoloader options passed to `composer install` with the configured autoloader mode
+func (c Contributor) dumpAutoloadOptions() []string {
+	options := append([]string{"--no-scripts", c.devOption()}, c.installAutoloadOptions()...)
+
+	for _, option := range c.composerConfig.AutoloaderOptions() {
+		if !composer.Contains(options, option) {
+			options = append(options, option)
+		}
+	}
+
+	return options
+}
+
+// installAutoloadOptions returns the `composer dump-autoload` equivalents of the autoloader options of the install
+// options
+func (c Contributor) installAutoloadOptions() []string {
+	var options []string
+	for _, option := range c.composerConfig.InstallOptions {
+		for _, mapping := range autoloadOptions {
+			installOption, dumpOption := mapping[0], mapping[1]
+			if option == installOption {
+				options = append(options, dumpOption)
+			} else if strings.HasSuffix(installOption, "=") && strings.HasPrefix(option, installOption) {
+				options = append(options, dumpOption+strings.TrimPrefix(option, installOption))
+			}
+		}
+	}
+	return options
+}
+
+func (c Contributor) devOption() string {
+	if composer.Contains(c.composerConfig.InstallOptions, "--no-dev") {
+		return "--no-dev"
+	}
+	return "--dev"
+}
+
+// scriptExitCode returns the exit code of a failed script, or -1 when it did not exit by itself
+func scriptExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitCode, ok := runner.ExitCode(err); ok {
+		return exitCode
+	}
+	return -1
+}
+
+// composerProgress matches the lines in which Composer continues with its own work after the scripts of an event, such
+// as "Generating autoload files" after pre-autoload-dump
+var composerProgress = regexp.MustCompile(`^(Installing dependencies|Loading composer repositories|Verifying lock file|Package operations:|Nothing to install|Writing lock file|Generating (optimized )?autoload files|Generated (optimized )?autoload files)`)
+
+// scriptLog follows the verbose output of `composer install`, which announces the scripts of an event as
+// "> post-install-cmd: ...", and logs every event with its duration and exit code. An event ends when the next one
+// starts, when Composer continues with its own work or when Composer exits.
+type scriptLog struct {
+	logger logger.Logger
+
+	mutex   sync.Mutex
+	partial string
+	event   string
+	start   time.Time
+}
+
+func (s *scriptLog) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lines := strings.Split(strings.ReplaceAll(s.partial+string(p), "\r", "\n"), "\n")
+	s.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		s.follow(runner.ParseEvent(line))
+	}
+
+	return len(p), nil
+}
+
+func (s *scriptLog) follow(event runner.Event) {
+	switch event.Type {
+	case runner.EventScript:
+		if event.ScriptEvent == "" || event.ScriptEvent == s.event {
+			return
+		}
+
+		s.finish(0)
+		s.event, s.start = event.ScriptEvent, time.Now()
+		s.logger.Body("Running %s scripts", s.event)
+	case runner.EventInstall, runner.EventUpdate, runner.EventRemove:
+		s.finish(0)
+	case runner.EventOutput:
+		if composerProgress.MatchString(event.Message) {
+			s.finish(0)
+		}
+	}
+}
+
+func (s *scriptLog) finish(exitCode int) {
+	if s.event == "" {
+		return
+	}
+
+	s.logger.Body("Finished %s scripts in %s with exit code %d", s.event, time.Since(s.start).Round(time.Millisecond), exitCode)
+	s.event = ""
+}
+
+// close ends the event that ran when Composer exited, which failed if Composer did, and names it in the error
+func (s *scriptLog) close(err error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.partial != "" {
+		s.follow(runner.ParseEvent(s.partial))
+		s.partial = ""
+	}
+
+	event, exitCode := s.event, scriptExitCode(err)
+	s.finish(exitCode)
+
+	if err != nil && event != "" {
+		return fmt.Errorf("composer script %s failed with exit code %d: %w", event, exitCode, err)
+	}
+	return err
+}
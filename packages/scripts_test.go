@@ -0,0 +1,203 @@
+package packages
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+	"github.com/paketo-buildpacks/php-composer/runner"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// recordingRunner records every command and fails the ones containing failOn
+type recordingRunner struct {
+	commands []string
+	failOn   string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
+	command := strings.Join(append([]string{bin}, args[1:]...), " ")
+	r.commands = append(r.commands, command)
+	if r.failOn != "" && strings.Contains(command, r.failOn) {
+		return runner.ExitError{Code: 1}
+	}
+	return nil
+}
+
+func (r *recordingRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
+	return "", r.Run(ctx, bin, dir, args...)
+}
+
+func TestUnitScripts(t *testing.T) {
+	spec.Run(t, "Scripts", testScripts, spec.Report(report.Terminal{}))
+}
+
+func testScripts(t *testing.T, when spec.G, it spec.S) {
+	var (
+		factory  *test.BuildFactory
+		recorder *recordingRunner
+		info     *bytes.Buffer
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		factory = test.NewBuildFactory(t)
+		test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), `{
+			"scripts": {
+				"pre-install-cmd": "echo pre",
+				"post-install-cmd": ["@php bin/console cache:clear"]
+			}
+		}`)
+		recorder = &recordingRunner{}
+		info = &bytes.Buffer{}
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv(composer.ScriptsEnv)).To(Succeed())
+		Expect(os.Unsetenv(composer.InstallOptionsEnv)).To(Succeed())
+		Expect(os.Unsetenv(composer.AutoloaderEnv)).To(Succeed())
+	})
+
+	newContributor := func() Contributor {
+		contributor, _, err := NewContributor(factory.Build, "/tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		contributor.composer.Runner = recorder
+		contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+		return contributor
+	}
+
+	it("lets Composer run all scripts itself when every event is allowed", func() {
+		Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev -o")).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(Equal([]string{"php install --no-progress --no-dev -o -v"}))
+	})
+
+	when("every event is allowed", func() {
+		var scripted *runner.ScriptedRunner
+
+		newScriptedContributor := func(step runner.Step) Contributor {
+			scripted = &runner.ScriptedRunner{Steps: []runner.Step{step}}
+			contributor := newContributor()
+			contributor.composer.Runner = scripted
+			return contributor
+		}
+
+		it("logs the script events Composer runs with their duration and exit code", func() {
+			contributor := newScriptedContributor(runner.Step{
+				Match: []string{"php", "install", "-v"},
+				Stderr: "> pre-install-cmd: echo pre\npre\nInstalling dependencies from lock file (including require-dev)\n" +
+					"Generating autoload files\n> post-install-cmd: @php bin/console cache:clear\n",
+			})
+
+			Expect(contributor.installPackages()).To(Succeed())
+			Expect(info.String()).To(MatchRegexp(`Running pre-install-cmd scripts\s+Finished pre-install-cmd scripts in \S+ with exit code 0`))
+			Expect(info.String()).To(MatchRegexp(`Running post-install-cmd scripts\s+Finished post-install-cmd scripts in \S+ with exit code 0`))
+		})
+
+		it("fails with the name and exit code of the failing event", func() {
+			contributor := newScriptedContributor(runner.Step{
+				Match:    []string{"php", "install"},
+				Stderr:   "Generating autoload files\n> post-install-cmd: @php bin/console cache:clear\nCache could not be cleared\n",
+				ExitCode: 255,
+			})
+
+			err := contributor.installPackages()
+			Expect(err).To(MatchError(HavePrefix("composer script post-install-cmd failed with exit code 255: ")))
+			Expect(info.String()).To(MatchRegexp(`Finished post-install-cmd scripts in \S+ with exit code 255`))
+		})
+
+		it("does not blame a script when Composer fails on its own", func() {
+			contributor := newScriptedContributor(runner.Step{
+				Match:    []string{"php", "install"},
+				Stderr:   "> pre-install-cmd: echo pre\nInstalling dependencies from lock file\nYour requirements could not be resolved\n",
+				ExitCode: 2,
+			})
+
+			err := contributor.installPackages()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("composer script"))
+			Expect(info.String()).To(MatchRegexp(`Finished pre-install-cmd scripts in \S+ with exit code 0`))
+		})
+	})
+
+	it("passes the configured autoloader mode to the install when every event is allowed", func() {
+		Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev")).To(Succeed())
+		Expect(os.Setenv(composer.AutoloaderEnv, composer.AutoloaderClassmapAuthoritative)).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(Equal([]string{"php install --no-progress --no-dev --optimize-autoloader --classmap-authoritative -v"}))
+	})
+
+	it("runs the allowed scripts around install and dump-autoload", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "pre-install-cmd,post-install-cmd")).To(Succeed())
+		Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev --optimize-autoloader")).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(Equal([]string{
+			"php run-script pre-install-cmd --no-dev",
+			"php install --no-progress --no-dev --optimize-autoloader --no-scripts --no-autoloader",
+			"php dump-autoload --no-scripts --no-dev --optimize",
+			"php run-script post-install-cmd --no-dev",
+		}))
+		Expect(info.String()).To(ContainSubstring("Running pre-install-cmd scripts"))
+		Expect(info.String()).To(MatchRegexp(`Finished post-install-cmd scripts in \S+ with exit code 0`))
+	})
+
+	it("dumps the autoloader in the configured mode", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "post-install-cmd")).To(Succeed())
+		Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev --optimize-autoloader")).To(Succeed())
+		Expect(os.Setenv(composer.AutoloaderEnv, composer.AutoloaderClassmapAuthoritative)).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(ContainElement("php dump-autoload --no-scripts --no-dev --optimize --classmap-authoritative"))
+	})
+
+	it("skips the events that are not allowed", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "post-install-cmd")).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(HaveLen(3))
+		Expect(recorder.commands[0]).To(HavePrefix("php install"))
+		Expect(recorder.commands[2]).To(Equal("php run-script post-install-cmd --no-dev"))
+		Expect(info.String()).To(ContainSubstring("Skipping pre-install-cmd scripts"))
+	})
+
+	it("runs post-install-cmd when the autoloader is not dumped", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "post-install-cmd")).To(Succeed())
+		Expect(os.Setenv(composer.InstallOptionsEnv, "--no-dev --no-autoloader")).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(Equal([]string{
+			"php install --no-progress --no-dev --no-autoloader --no-scripts",
+			"php run-script post-install-cmd --no-dev",
+		}))
+	})
+
+	it("runs no scripts when the policy is none", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "none")).To(Succeed())
+
+		Expect(newContributor().installPackages()).To(Succeed())
+		Expect(recorder.commands).To(HaveLen(2))
+	})
+
+	it("fails with the name and exit code of the failing event", func() {
+		Expect(os.Setenv(composer.ScriptsEnv, "post-install-cmd")).To(Succeed())
+		recorder.failOn = "post-install-cmd"
+
+		err := newContributor().installPackages()
+		Expect(err).To(MatchError(ContainSubstring("composer script post-install-cmd failed with exit code 1")))
+		Expect(info.String()).To(ContainSubstring("Finished post-install-cmd scripts in"))
+	})
+}
@@ -0,0 +1,219 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/php-composer/composer"
+)
+
+const (
+	// vcsTimeout bounds every request to a VCS host API
+	vcsTimeout = 5 * time.Second
+
+	// vcsAttempts is how often a token check is attempted before the API is considered unreachable
+	vcsAttempts = 3
+)
+
+// vcsBackoff is the delay before the first retry of a token check, which doubles with every further retry
+var vcsBackoff = time.Second
+
+// VCSChecker checks a token for a VCS host before it is handed to Composer
+type VCSChecker interface {
+	// Name is the name of the host in the build log, e.g. GitHub
+	Name() string
+
+	// Host is the host Composer authenticates against, e.g. github.com
+	Host() string
+
+	// Check asks the host API about the token. An error means the API could not be asked, not that the token is
+	// invalid.
+	Check(token string) (TokenStatus, error)
+
+	// Configure hands the token to Composer
+	Configure(c composer.Composer, token string) error
+}
+
+// TokenStatus is what a VCS host reports about a token
+type TokenStatus struct {
+	Valid bool
+
+	// Scopes are the scopes granted to the token, if the host reports them
+	Scopes []string
+
+	// Limit and Remaining are the API quota of the token. A limit of zero means the host does not report a quota.
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimited reports whether the token has used up its API quota
+func (s TokenStatus) RateLimited() bool {
+	return s.Limit > 0 && s.Remaining <= 0
+}
+
+// vcsToken is a token for a VCS host, taken from the environment
+type vcsToken struct {
+	env     string
+	checker VCSChecker
+}
+
+// vcsTokens returns a token entry for every VCS host whose token environment variable is set
+func (c Contributor) vcsTokens() []vcsToken {
+	return []vcsToken{
+		{env: "COMPOSER_GITHUB_OAUTH_TOKEN", checker: NewGitHub(c.composerConfig.GitHubURL)},
+		{env: "COMPOSER_GITLAB_TOKEN", checker: NewGitLab(c.composerConfig.GitLabURL)},
+		{env: "COMPOSER_BITBUCKET_TOKEN", checker: NewBitbucket()},
+	}
+}
+
+// configureVCSTokens checks the token of each VCS host and hands the valid ones to Composer. A host that cannot be
+// reached or refuses the check only causes a warning, and its token is configured unchecked, as it is when BP_COMPOSER_SKIP_TOKEN_CHECK is
+// set for offline builds.
+func (c Contributor) configureVCSTokens() error {
+	logger := c.composer.Logger
+
+	for _, t := range c.vcsTokens() {
+		token := os.Getenv(t.env)
+		if token == "" {
+			continue
+		}
+
+		name, host := t.checker.Name(), t.checker.Host()
+		if c.composerConfig.SkipTokenCheck {
+			logger.Body("Using the %s token for %s without checking it", name, host)
+			if err := t.checker.Configure(c.composer, token); err != nil {
+				return err
+			}
+			continue
+		}
+
+		status, err := c.checkToken(t.checker, token)
+		if err != nil {
+			logger.BodyWarning("Unable to check the token with the %s API for %s after %d attempts, configuring the token unchecked: %s", name, host, vcsAttempts, err)
+			if err := t.checker.Configure(c.composer, token); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !status.Valid {
+			logger.BodyWarning("The %s token for %s from %s was rejected, Composer will continue without it", name, host, t.env)
+			continue
+		}
+
+		logger.Body("Using the %s token for %s%s", name, host, describeToken(status))
+		if err := t.checker.Configure(c.composer, token); err != nil {
+			return err
+		}
+
+		if status.RateLimited() {
+			logger.BodyWarning("The %s API rate limit of the token has been exceeded until %s. "+
+				"Composer will continue by downloading from source, which might result in slower downloads.", name, status.Reset.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+// checkToken checks a token, retrying with exponential backoff while the API cannot be reached
+func (c Contributor) checkToken(checker VCSChecker, token string) (TokenStatus, error) {
+	backoff := vcsBackoff
+	for attempt := 1; ; attempt++ {
+		status, err := checker.Check(token)
+		if err == nil || attempt == vcsAttempts {
+			return status, err
+		}
+
+		c.composer.Logger.Debug("Checking the %s token for %s failed, retrying in %s: %s", checker.Name(), checker.Host(), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// describeToken summarizes the scopes and the quota of a token for the build log
+func describeToken(status TokenStatus) string {
+	var details []string
+	if len(status.Scopes) > 0 {
+		details = append(details, "scopes: "+strings.Join(status.Scopes, ", "))
+	}
+	if status.Limit > 0 {
+		details = append(details, fmt.Sprintf("%d of %d requests remaining", status.Remaining, status.Limit))
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, "; ") + ")"
+}
+
+// vcsRequest sends an authenticated GET request to a VCS host API and returns the response with its body read
+func vcsRequest(url string, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = header
+
+	client := http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}, Timeout: vcsTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// checkResponse rejects responses that say nothing about the token, such as server errors. Only a 401, or a 403 with
+// GitHub's "Bad credentials" message, rejects the token. Any other 403, e.g. of an exhausted quota, an IP allow list
+// or a missing scope, means that the token could not be checked.
+func checkResponse(resp *http.Response, body []byte) error {
+	switch {
+	case resp.StatusCode < 300, resp.StatusCode == http.StatusUnauthorized:
+		return nil
+	case resp.StatusCode == http.StatusForbidden && strings.Contains(string(body), "Bad credentials"):
+		return nil
+	default:
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// decodeJSON decodes a successful response body
+func decodeJSON(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unable to parse the response: %w", err)
+	}
+	return nil
+}
+
+// splitScopes splits a scopes header such as "repo, read:org"
+func splitScopes(header string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// headerInt reads a numeric header, returning zero when it is missing
+func headerInt(header http.Header, key string) int {
+	value, err := strconv.Atoi(header.Get(key))
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,258 @@
+package packages
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/test"
+	"github.com/paketo-buildpacks/php-composer/composer"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitVCS(t *testing.T) {
+	spec.Run(t, "VCS", testVCS, spec.Report(report.Terminal{}))
+}
+
+func testVCS(t *testing.T, when spec.G, it spec.S) {
+	var (
+		responses map[string]string
+		headers   http.Header
+		status    int
+		failures  int
+		requests  int
+		ts        *httptest.Server
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		responses = map[string]string{}
+		headers = http.Header{}
+		status = http.StatusOK
+		failures = 0
+		requests = 0
+		vcsBackoff = time.Millisecond
+
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= failures {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			response, ok := responses[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			for key, values := range headers {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			fmt.Fprintln(w, response)
+		}))
+	})
+
+	it.After(func() {
+		ts.Close()
+		vcsBackoff = time.Second
+	})
+
+	when("configuring tokens", func() {
+		var (
+			factory *test.BuildFactory
+			runner  *recordingRunner
+			info    *bytes.Buffer
+		)
+
+		it.Before(func() {
+			factory = test.NewBuildFactory(t)
+			test.WriteFile(t, filepath.Join(factory.Build.Application.Root, composer.ComposerJSON), "{}")
+			runner = &recordingRunner{}
+			info = &bytes.Buffer{}
+
+			Expect(os.Setenv(composer.GitHubURLEnv, ts.URL)).To(Succeed())
+			Expect(os.Setenv("COMPOSER_GITHUB_OAUTH_TOKEN", "FAKE")).To(Succeed())
+		})
+
+		it.After(func() {
+			for _, env := range []string{composer.GitHubURLEnv, composer.SkipTokenCheckEnv, "COMPOSER_GITHUB_OAUTH_TOKEN"} {
+				Expect(os.Unsetenv(env)).To(Succeed())
+			}
+		})
+
+		configure := func() error {
+			contributor, _, err := NewContributor(factory.Build, "/tmp")
+			Expect(err).NotTo(HaveOccurred())
+
+			contributor.composer.Runner = runner
+			contributor.composer.Logger = logger.Logger{Logger: bplogger.NewLogger(nil, info)}
+			return contributor.configureVCSTokens()
+		}
+
+		host := func() string {
+			return strings.TrimPrefix(ts.URL, "http://")
+		}
+
+		it("configures valid tokens and reports their scopes and quota", func() {
+			headers.Set("X-OAuth-Scopes", "repo")
+			responses["/api/v3/rate_limit"] = `{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1560873755}}}`
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(Equal([]string{
+				"php config -g github-domains github.com " + host(),
+				"php config -g github-oauth." + host() + " FAKE",
+			}))
+			Expect(info.String()).To(ContainSubstring("Using the GitHub Enterprise token for " + host() + " (scopes: repo; 4990 of 5000 requests remaining)"))
+		})
+
+		it("warns when the rate limit is exceeded", func() {
+			responses["/api/v3/rate_limit"] = `{"resources": {"core": {"limit": 5000, "remaining": 0, "reset": 1560873755}}}`
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(HaveLen(2))
+			Expect(info.String()).To(ContainSubstring("The GitHub Enterprise API rate limit of the token has been exceeded until 2019-06-18T16:02:35Z"))
+		})
+
+		it("skips rejected tokens", func() {
+			status = http.StatusUnauthorized
+			responses["/api/v3/rate_limit"] = `{"message": "Bad credentials"}`
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(BeEmpty())
+			Expect(info.String()).To(ContainSubstring("The GitHub Enterprise token for " + host() + " from COMPOSER_GITHUB_OAUTH_TOKEN was rejected"))
+		})
+
+		it("configures the token unchecked when the API is unreachable", func() {
+			ts.Close()
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(ContainElement("php config -g github-oauth." + host() + " FAKE"))
+			Expect(info.String()).To(ContainSubstring("Unable to check the token with the GitHub Enterprise API for " + host() + " after 3 attempts, configuring the token unchecked"))
+		})
+
+		it("configures the token unchecked when the API forbids the check", func() {
+			status = http.StatusForbidden
+			responses["/api/v3/rate_limit"] = `{"message": "Although you appear to have the correct authorization credentials, the organization has an IP allow list enabled"}`
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(ContainElement("php config -g github-oauth." + host() + " FAKE"))
+			Expect(info.String()).To(ContainSubstring("configuring the token unchecked: unexpected response 403 Forbidden"))
+		})
+
+		it("skips tokens GitHub forbids as bad credentials", func() {
+			status = http.StatusForbidden
+			responses["/api/v3/rate_limit"] = `{"message": "Bad credentials"}`
+
+			Expect(configure()).To(Succeed())
+			Expect(runner.commands).To(BeEmpty())
+			Expect(info.String()).To(ContainSubstring("was rejected"))
+		})
+
+		it("retries while the API fails", func() {
+			failures = 2
+			responses["/api/v3/rate_limit"] = `{"resources": {"core": {"limit": 5000, "remaining": 4990, "reset": 1560873755}}}`
+
+			Expect(configure()).To(Succeed())
+			Expect(requests).To(Equal(3))
+			Expect(info.String()).To(ContainSubstring("Using the GitHub Enterprise token for " + host() + " (4990 of 5000 requests remaining)"))
+		})
+
+		it("gives up after the last attempt", func() {
+			failures = 5
+
+			Expect(configure()).To(Succeed())
+			Expect(requests).To(Equal(3))
+			Expect(runner.commands).To(ContainElement("php config -g github-oauth." + host() + " FAKE"))
+			Expect(info.String()).To(ContainSubstring("unexpected response 502 Bad Gateway"))
+		})
+
+		it("skips the check when asked to", func() {
+			Expect(os.Setenv(composer.SkipTokenCheckEnv, "true")).To(Succeed())
+
+			Expect(configure()).To(Succeed())
+			Expect(requests).To(BeZero())
+			Expect(runner.commands).To(ContainElement("php config -g github-oauth." + host() + " FAKE"))
+			Expect(info.String()).To(ContainSubstring("Using the GitHub Enterprise token for " + host() + " without checking it"))
+		})
+	})
+
+	when("checking GitLab tokens", func() {
+		it("reports the scopes of the token and the quota", func() {
+			headers.Set("RateLimit-Limit", "2000")
+			headers.Set("RateLimit-Remaining", "1999")
+			responses["/api/v4/personal_access_tokens/self"] = `{"scopes": ["read_api", "read_repository"], "active": true}`
+
+			tokenStatus, err := NewGitLab(ts.URL).Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus.Valid).To(BeTrue())
+			Expect(tokenStatus.Scopes).To(Equal([]string{"read_api", "read_repository"}))
+			Expect(tokenStatus.Limit).To(Equal(2000))
+			Expect(tokenStatus.Remaining).To(Equal(1999))
+		})
+
+		it("rejects revoked tokens", func() {
+			responses["/api/v4/personal_access_tokens/self"] = `{"scopes": ["read_api"], "active": false}`
+
+			tokenStatus, err := NewGitLab(ts.URL).Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus.Valid).To(BeFalse())
+		})
+
+		it("falls back to the current user on older GitLab versions", func() {
+			responses["/api/v4/user"] = `{"username": "ci"}`
+
+			tokenStatus, err := NewGitLab(ts.URL).Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus.Valid).To(BeTrue())
+			Expect(tokenStatus.Scopes).To(BeEmpty())
+		})
+
+		it("configures the token and the domain of a self-managed GitLab", func() {
+			runner := &recordingRunner{}
+			Expect(NewGitLab("https://gitlab.example.com").Configure(composer.Composer{Runner: runner}, "FAKE")).To(Succeed())
+			Expect(runner.commands).To(Equal([]string{
+				"php config -g gitlab-domains gitlab.com gitlab.example.com",
+				"php config -g gitlab-token.gitlab.example.com FAKE",
+			}))
+		})
+	})
+
+	when("checking Bitbucket tokens", func() {
+		it("reports the scopes of the token", func() {
+			headers.Set("X-OAuth-Scopes", "repository, account")
+			responses["/user"] = `{"username": "ci"}`
+
+			tokenStatus, err := Bitbucket{apiURL: ts.URL}.Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus).To(Equal(TokenStatus{Valid: true, Scopes: []string{"repository", "account"}}))
+		})
+
+		it("rejects tokens Bitbucket does not accept", func() {
+			status = http.StatusUnauthorized
+			responses["/user"] = `{"type": "error"}`
+
+			tokenStatus, err := Bitbucket{apiURL: ts.URL}.Check("FAKE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenStatus.Valid).To(BeFalse())
+		})
+
+		it("configures the token as x-token-auth credentials", func() {
+			runner := &recordingRunner{}
+			Expect(NewBitbucket().Configure(composer.Composer{Runner: runner}, "FAKE")).To(Succeed())
+			Expect(runner.commands).To(Equal([]string{"php config -g http-basic.bitbucket.org x-token-auth FAKE"}))
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EventType is the kind of a line of Composer output
+type EventType string
+
+const (
+	EventInstall  EventType = "install"
+	EventUpdate   EventType = "update"
+	EventRemove   EventType = "remove"
+	EventDownload EventType = "download"
+	EventScript   EventType = "script"
+	EventWarning  EventType = "warning"
+	EventOutput   EventType = "output"
+)
+
+// Event is a parsed line of Composer output
+type Event struct {
+	Type        EventType
+	Package     string
+	Version     string
+	FromVersion string
+
+	// ScriptEvent is the Composer event a script runs for, if Composer is verbose enough to report it
+	ScriptEvent string
+
+	// Message is the command of a script, the text of a warning, the action Composer takes for a package or the line
+	// itself
+	Message string
+}
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	// e.g. "- Installing monolog/monolog (1.25.1): Extracting archive" or "- Upgrading psr/log (1.1.2 => 1.1.4)"
+	operationLine = regexp.MustCompile(`^-\s+(Installing|Updating|Upgrading|Downgrading|Removing|Downloading)\s+(\S+)\s+\(([^)]*)\)(?::\s*(.*))?$`)
+
+	// e.g. "> post-autoload-dump: Illuminate\Foundation\ComposerScripts::postAutoloadDump" or "> @php artisan optimize"
+	scriptLine = regexp.MustCompile(`^>\s+(?:([a-z]+(?:-[a-z]+)+):\s+)?(.+)$`)
+
+	warningLine = regexp.MustCompile(`(?i)^(?:warning|deprecation warning|deprecation notice|notice)\s*:\s*(.*)$`)
+)
+
+var operations = map[string]EventType{
+	"Installing":  EventInstall,
+	"Updating":    EventUpdate,
+	"Upgrading":   EventUpdate,
+	"Downgrading": EventUpdate,
+	"Removing":    EventRemove,
+	"Downloading": EventDownload,
+}
+
+// ParseEvent parses a line of Composer output. Lines that are not recognized are output events.
+func ParseEvent(line string) Event {
+	line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+
+	if match := operationLine.FindStringSubmatch(line); match != nil {
+		event := Event{Type: operations[match[1]], Package: match[2], Version: match[3], Message: match[4]}
+		if versions := strings.SplitN(match[3], " => ", 2); len(versions) == 2 {
+			event.FromVersion, event.Version = versions[0], versions[1]
+		}
+		return event
+	}
+
+	if match := scriptLine.FindStringSubmatch(line); match != nil {
+		return Event{Type: EventScript, ScriptEvent: match[1], Message: match[2]}
+	}
+
+	if match := warningLine.FindStringSubmatch(line); match != nil {
+		return Event{Type: EventWarning, Message: match[1]}
+	}
+
+	if strings.Contains(line, " is abandoned, you should avoid using it") {
+		return Event{Type: EventWarning, Message: line}
+	}
+
+	return Event{Type: EventOutput, Message: line}
+}
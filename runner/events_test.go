@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitEvents(t *testing.T) {
+	spec.Run(t, "Events", testEvents, spec.Report(report.Terminal{}))
+}
+
+func testEvents(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("parses package operations", func() {
+		Expect(ParseEvent("  - Installing monolog/monolog (1.25.1): Extracting archive")).To(Equal(Event{
+			Type: EventInstall, Package: "monolog/monolog", Version: "1.25.1", Message: "Extracting archive",
+		}))
+		Expect(ParseEvent("  - Upgrading psr/log (1.1.2 => 1.1.4)")).To(Equal(Event{
+			Type: EventUpdate, Package: "psr/log", FromVersion: "1.1.2", Version: "1.1.4",
+		}))
+		Expect(ParseEvent("  - Downgrading psr/log (1.1.4 => 1.1.2)").FromVersion).To(Equal("1.1.4"))
+		Expect(ParseEvent("  - Removing symfony/polyfill-php72 (v1.17.0)")).To(Equal(Event{
+			Type: EventRemove, Package: "symfony/polyfill-php72", Version: "v1.17.0",
+		}))
+		Expect(ParseEvent("  - Downloading monolog/monolog (1.25.1)").Type).To(Equal(EventDownload))
+	})
+
+	it("parses scripts with and without their event", func() {
+		Expect(ParseEvent(`> post-autoload-dump: Illuminate\Foundation\ComposerScripts::postAutoloadDump`)).To(Equal(Event{
+			Type: EventScript, ScriptEvent: "post-autoload-dump", Message: `Illuminate\Foundation\ComposerScripts::postAutoloadDump`,
+		}))
+		Expect(ParseEvent("> @php artisan package:discover --ansi")).To(Equal(Event{
+			Type: EventScript, Message: "@php artisan package:discover --ansi",
+		}))
+	})
+
+	it("parses warnings", func() {
+		Expect(ParseEvent("Warning: The lock file is not up to date with the latest changes in composer.json.")).To(Equal(Event{
+			Type: EventWarning, Message: "The lock file is not up to date with the latest changes in composer.json.",
+		}))
+		Expect(ParseEvent("Package fzaninotto/faker is abandoned, you should avoid using it. No replacement was suggested.").Type).To(Equal(EventWarning))
+	})
+
+	it("strips colors and keeps other lines as output", func() {
+		Expect(ParseEvent("\x1b[32mGenerating autoload files\x1b[39m")).To(Equal(Event{Type: EventOutput, Message: "Generating autoload files"}))
+	})
+}
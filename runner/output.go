@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+type outputKey struct{}
+
+// WithOutput returns a context under which Run copies the output of the command to w as well, e.g. to follow the
+// scripts Composer runs. Stdout and stderr may write to w concurrently.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// observed adds the writer of the context, if there is one, to the writers of a command
+func observed(ctx context.Context, writers ...io.Writer) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok && w != nil {
+		writers = append(writers, w)
+	}
+	return io.MultiWriter(writers...)
+}
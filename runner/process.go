@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tailLines is how many lines of output a TimeoutError reports
+const tailLines = 20
+
+// KillGrace is how long a command gets to exit after SIGTERM before its process group is killed
+var KillGrace = 10 * time.Second
+
+// TimeoutError reports a command that was stopped because its context expired. The command has its credentials
+// redacted.
+type TimeoutError struct {
+	Command string
+	Elapsed time.Duration
+	Output  []string
+	Cause   error
+}
+
+func (e TimeoutError) Error() string {
+	reason := fmt.Sprintf("timed out after %s", e.Elapsed.Round(time.Second))
+	if errors.Is(e.Cause, context.Canceled) {
+		reason = fmt.Sprintf("was cancelled after %s", e.Elapsed.Round(time.Second))
+	}
+
+	message := fmt.Sprintf("`%s` %s", e.Command, reason)
+	if len(e.Output) > 0 {
+		message += ", last output:\n    " + strings.Join(e.Output, "\n    ")
+	}
+	return message
+}
+
+func (e TimeoutError) Unwrap() error {
+	return e.Cause
+}
+
+// CommandError reports a command that failed, together with its last lines of output. The command has its
+// credentials redacted.
+type CommandError struct {
+	Command string
+	Output  []string
+	Err     error
+}
+
+func (e CommandError) Error() string {
+	return fmt.Sprintf("`%s` failed: %s", e.Command, e.Err)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
+
+// execute runs a command in its own process group. When the context expires, the group gets SIGTERM and, if it is
+// still running after KillGrace, SIGKILL, so that processes started by Composer scripts stop as well.
+func execute(ctx context.Context, cmd *exec.Cmd, tail *tailWriter) error {
+	setProcessGroup(cmd)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return CommandError{Command: redactCommand(cmd.Args), Output: tail.lines(), Err: err}
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	_ = terminateProcessGroup(cmd)
+	select {
+	case <-done:
+	case <-time.After(KillGrace):
+		_ = killProcessGroup(cmd)
+		<-done
+	}
+
+	return TimeoutError{
+		Command: redactCommand(cmd.Args),
+		Elapsed: time.Since(start),
+		Output:  tail.lines(),
+		Cause:   ctx.Err(),
+	}
+}
+
+// tailWriter keeps the last lines written to it. Stdout and stderr may write concurrently.
+type tailWriter struct {
+	mutex   sync.Mutex
+	partial string
+	tail    []string
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := strings.Split(strings.ReplaceAll(t.partial+string(p), "\r", "\n"), "\n")
+	t.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		t.tail = append(t.tail, line)
+		if len(t.tail) > tailLines {
+			t.tail = t.tail[1:]
+		}
+	}
+
+	return len(p), nil
+}
+
+func (t *tailWriter) lines() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := append([]string{}, t.tail...)
+	if strings.TrimSpace(t.partial) != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > tailLines {
+		lines = lines[len(lines)-tailLines:]
+	}
+	return lines
+}
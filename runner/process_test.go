@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitProcess(t *testing.T) {
+	spec.Run(t, "Process", testProcess, spec.Report(report.Terminal{}))
+}
+
+func testProcess(t *testing.T, when spec.G, it spec.S) {
+	var (
+		f         *test.BuildFactory
+		killGrace time.Duration
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		f = test.NewBuildFactory(t)
+		killGrace = KillGrace
+	})
+
+	it.After(func() {
+		KillGrace = killGrace
+	})
+
+	when("the context expires", func() {
+		it("stops the command and reports its last output", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := ComposerRunner{Logger: f.Build.Logger}.RunWithOutput(ctx, "sh", "", "-c", "echo started; sleep 30")
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+
+			var timeoutErr TimeoutError
+			Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+			Expect(timeoutErr.Command).To(Equal("sh -c echo started; sleep 30"))
+			Expect(timeoutErr.Output).To(Equal([]string{"started"}))
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix("`sh -c echo started; sleep 30` timed out after "))
+			Expect(err.Error()).To(HaveSuffix(", last output:\n    started"))
+		})
+
+		it("redacts the credentials of the command", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := ComposerRunner{Logger: f.Build.Logger}.Run(ctx, "sh", "", "-c", "sleep 30", "config", "-g", "github-oauth.github.com", "s3cret-token")
+			Expect(errors.As(err, &TimeoutError{})).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix("`sh -c sleep 30 config -g github-oauth.github.com redacted` timed out after "))
+			Expect(err.Error()).NotTo(ContainSubstring("s3cret-token"))
+		})
+
+		it("kills the process group when SIGTERM is ignored", func() {
+			KillGrace = 100 * time.Millisecond
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			// the background sleep keeps the output open until it is killed as well
+			start := time.Now()
+			err := StructuredRunner{Logger: f.Build.Logger}.Run(ctx, "sh", "", "-c", `trap "" TERM; sleep 30 & wait`)
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			Expect(errors.As(err, &TimeoutError{})).To(BeTrue())
+		})
+
+		it("reports cancellation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := ComposerRunner{Logger: f.Build.Logger}.Run(ctx, "sleep", "", "30")
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix("`sleep 30` was cancelled after "))
+		})
+	})
+
+	it("reports the last output of failed commands", func() {
+		err := ComposerRunner{Logger: f.Build.Logger}.Run(context.Background(), "sh", "", "-c", "echo resolving; echo conflict >&2; exit 2")
+
+		var commandErr CommandError
+		Expect(errors.As(err, &commandErr)).To(BeTrue())
+		Expect(commandErr.Command).To(Equal("sh -c echo resolving; echo conflict >&2; exit 2"))
+		Expect(commandErr.Output).To(ConsistOf("resolving", "conflict"))
+
+		var exitErr *exec.ExitError
+		Expect(errors.As(err, &exitErr)).To(BeTrue())
+		Expect(exitErr.ExitCode()).To(Equal(2))
+	})
+
+	it("redacts the credentials of failed commands", func() {
+		err := ComposerRunner{Logger: f.Build.Logger}.Run(context.Background(), "sh", "", "-c", "exit 1", "config", "-g", "github-oauth.github.com", "s3cret-token")
+
+		Expect(err).To(MatchError("`sh -c exit 1 config -g github-oauth.github.com redacted` failed: exit status 1"))
+		Expect(err.Error()).NotTo(ContainSubstring("s3cret-token"))
+	})
+
+	it("copies the output to the writer of the context", func() {
+		output := &bytes.Buffer{}
+		ctx := WithOutput(context.Background(), output)
+
+		Expect(ComposerRunner{Logger: f.Build.Logger}.Run(ctx, "sh", "", "-c", "echo installed; echo warned >&2")).To(Succeed())
+		Expect(strings.Fields(output.String())).To(ConsistOf("installed", "warned"))
+	})
+
+	it("keeps the last lines of output", func() {
+		tail := &tailWriter{}
+		for i := 1; i <= 25; i++ {
+			fmt.Fprintf(tail, "line %d\r\n", i)
+		}
+		fmt.Fprint(tail, "partial")
+
+		lines := tail.lines()
+		Expect(lines).To(HaveLen(tailLines))
+		Expect(lines[0]).To(Equal("line 7"))
+		Expect(lines[tailLines-1]).To(Equal("partial"))
+	})
+}
@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import (
+	"os/exec"
+)
+
+// Windows has no process groups to signal, so the command itself is killed
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces credentials in command lines
+const redactedValue = "redacted"
+
+// secretSettings are the prefixes of the `composer config` settings whose values are credentials
+var secretSettings = []string{"github-oauth.", "gitlab-token.", "gitlab-oauth.", "http-basic.", "bearer."}
+
+// urlPassword matches the password of a URL, also inside of a JSON value such as a repository definition
+var urlPassword = regexp.MustCompile(`(://[^/:@\s"']*):[^/@\s"']*@`)
+
+// RedactArgs returns a copy of a command line without the credentials it hands to Composer: the values of the
+// credential settings of `composer config` and the passwords of URLs, such as those of mirrors
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+
+	config, secret := false, false
+	for i, arg := range args {
+		switch {
+		case secret:
+			redacted[i] = redactedValue
+			continue
+		case arg == "config":
+			config = true
+		case config && isSecretSetting(arg):
+			secret = true
+		}
+		redacted[i] = urlPassword.ReplaceAllString(arg, "${1}:"+redactedValue+"@")
+	}
+
+	return redacted
+}
+
+// redactCommand returns a command line as it would be typed, without its credentials
+func redactCommand(args []string) string {
+	return strings.Join(RedactArgs(args), " ")
+}
+
+func isSecretSetting(key string) bool {
+	for _, prefix := range secretSettings {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
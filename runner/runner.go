@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"os/exec"
@@ -10,9 +11,10 @@ import (
 	"github.com/cloudfoundry/libcfbuildpack/logger"
 )
 
+// Runner runs commands until they finish or the context expires
 type Runner interface {
-	Run(bin, dir string, args ...string) error
-	RunWithOutput(bin, dir string, args ...string) (string, error)
+	Run(ctx context.Context, bin, dir string, args ...string) error
+	RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error)
 }
 
 type ComposerRunner struct {
@@ -21,7 +23,7 @@ type ComposerRunner struct {
 	Err    io.Writer
 }
 
-func (r ComposerRunner) Run(bin, dir string, args ...string) error {
+func (r ComposerRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
 	var cmd *exec.Cmd
 	if len(args) > 0 {
 		r.Logger.Debug("Running `%s %s` from directory '%s'", bin, strings.Join(args, " "), dir)
@@ -32,23 +34,24 @@ func (r ComposerRunner) Run(bin, dir string, args ...string) error {
 	}
 
 	cmd.Dir = dir
+	tail := &tailWriter{}
 
 	if r.Out != nil {
-		cmd.Stdout = io.MultiWriter(os.Stdout, r.Out)
+		cmd.Stdout = observed(ctx, os.Stdout, r.Out, tail)
 	} else {
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = observed(ctx, os.Stdout, tail)
 	}
 
 	if r.Err != nil {
-		cmd.Stderr = io.MultiWriter(os.Stderr, r.Err)
+		cmd.Stderr = observed(ctx, os.Stderr, r.Err, tail)
 	} else {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = observed(ctx, os.Stderr, tail)
 	}
 
-	return cmd.Run()
+	return execute(ctx, cmd, tail)
 }
 
-func (r ComposerRunner) RunWithOutput(bin, dir string, args ...string) (string, error) {
+func (r ComposerRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
 	var cmd *exec.Cmd
 	if len(args) > 0 {
 		r.Logger.Debug("Running `%s %s` from directory '%s'", bin, strings.Join(args, " "), dir)
@@ -60,15 +63,16 @@ func (r ComposerRunner) RunWithOutput(bin, dir string, args ...string) (string,
 
 	cmd.Dir = dir
 	buf := bytes.Buffer{}
-	cmd.Stdout = &buf
+	tail := &tailWriter{}
+	cmd.Stdout = io.MultiWriter(&buf, tail)
 
 	if r.Err != nil {
-		cmd.Stderr = io.MultiWriter(os.Stderr, r.Err)
+		cmd.Stderr = io.MultiWriter(os.Stderr, r.Err, tail)
 	} else {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, tail)
 	}
 
-	err := cmd.Run()
+	err := execute(ctx, cmd, tail)
 	// this is on purpose, we return whatever is in the buffer regardless of an error occurring
 	//  this defers handling of the error to the caller, see CheckPlatformReqs in composer.go
 	return buf.String(), err
@@ -77,18 +81,21 @@ func (r ComposerRunner) RunWithOutput(bin, dir string, args ...string) (string,
 type FakeRunner struct {
 	Arguments []string
 	Cwd       string
+	Context   context.Context
 	Out       *bytes.Buffer
 	Err       error
 }
 
-func (f *FakeRunner) Run(bin, dir string, args ...string) error {
+func (f *FakeRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
 	f.Arguments = append([]string{bin}, args...)
 	f.Cwd = dir
+	f.Context = ctx
 	return f.Err
 }
 
-func (f *FakeRunner) RunWithOutput(bin, dir string, args ...string) (string, error) {
+func (f *FakeRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
 	f.Arguments = append([]string{bin}, args...)
 	f.Cwd = dir
+	f.Context = ctx
 	return f.Out.String(), f.Err
 }
\ No newline at end of file
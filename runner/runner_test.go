@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"github.com/cloudfoundry/libcfbuildpack/test"
 	"github.com/sclevine/spec/report"
 	"testing"
@@ -33,7 +34,7 @@ func testRunner(t *testing.T, when spec.G, it spec.S) {
 				Logger: f.Build.Logger,
 			}
 
-			err := runner.Run("echo", "", "Hello")
+			err := runner.Run(context.Background(), "echo", "", "Hello")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(stdout.String()).To(Equal("Hello\n"))
@@ -42,7 +43,7 @@ func testRunner(t *testing.T, when spec.G, it spec.S) {
 			stdout.Reset()
 			stderr.Reset()
 
-			err = runner.Run("cat", "", "/does/not/exist.txt")
+			err = runner.Run(context.Background(), "cat", "", "/does/not/exist.txt")
 
 			Expect(err).To(HaveOccurred())
 			Expect(stdout.String()).To(BeEmpty())
@@ -59,7 +60,7 @@ func testRunner(t *testing.T, when spec.G, it spec.S) {
 				Logger: f.Build.Logger,
 			}
 
-			output, err := runner.RunWithOutput("echo", "", "Hello")
+			output, err := runner.RunWithOutput(context.Background(), "echo", "", "Hello")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(output).To(Equal("Hello\n"))
@@ -67,7 +68,7 @@ func testRunner(t *testing.T, when spec.G, it spec.S) {
 
 			stderr.Reset()
 
-			output, err = runner.RunWithOutput("cat", "", "/does/not/exist.txt")
+			output, err = runner.RunWithOutput(context.Background(), "cat", "", "/does/not/exist.txt")
 
 			Expect(err).To(HaveOccurred())
 			Expect(output).To(BeEmpty())
@@ -0,0 +1,292 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ExitError is the error of a scripted command that exits with a non-zero code
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e ExitError) ExitCode() int {
+	return e.Code
+}
+
+// ExitCode returns the exit code of a command that ran and failed, whether it ran for real or was scripted
+func ExitCode(err error) (int, bool) {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// Step is an expected command and the output and exit code it responds with
+type Step struct {
+	// Match are arguments the command line must contain in this order, e.g. ["php", "install"]. Ordered runners
+	// require the command line without absolute paths to be exactly Match. Credentials are matched in their redacted
+	// form.
+	Match    []string `json:"match"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code,omitempty"`
+}
+
+// Call is a command a ScriptedRunner was asked to run
+type Call struct {
+	Bin  string
+	Dir  string
+	Args []string
+}
+
+// CommandLine returns the command as it would be typed, with its credentials redacted
+func (c Call) CommandLine() string {
+	return redactCommand(append([]string{c.Bin}, c.Args...))
+}
+
+// ScriptedRunner records every call and answers it with the first unused step that matches, so that tests can run
+// flows of several Composer commands without PHP. Unexpected commands fail.
+type ScriptedRunner struct {
+	Steps []Step
+
+	// Ordered requires the commands to arrive in the order of the steps and with exactly the arguments of their match,
+	// as when replaying a recorded session
+	Ordered bool
+
+	// Out and Err receive the scripted output, as they do for a ComposerRunner
+	Out io.Writer
+	Err io.Writer
+
+	mutex sync.Mutex
+	used  []bool
+	calls []Call
+}
+
+// NewReplayRunner returns a runner that expects the commands of a recorded session in their original order
+func NewReplayRunner(session Session) *ScriptedRunner {
+	return &ScriptedRunner{Steps: session.Steps, Ordered: true}
+}
+
+func (r *ScriptedRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
+	step, err := r.next(ctx, bin, dir, args)
+	if err != nil {
+		return err
+	}
+
+	if r.Out != nil {
+		_, _ = io.WriteString(r.Out, step.Stdout)
+	}
+	_, _ = io.WriteString(observed(ctx), step.Stdout)
+	_, _ = io.WriteString(observed(ctx), step.Stderr)
+	return r.result(bin, args, step)
+}
+
+func (r *ScriptedRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
+	step, err := r.next(ctx, bin, dir, args)
+	if err != nil {
+		return "", err
+	}
+
+	return step.Stdout, r.result(bin, args, step)
+}
+
+// Calls returns the commands the runner was asked to run, in order
+func (r *ScriptedRunner) Calls() []Call {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Call{}, r.calls...)
+}
+
+// Pending returns the steps that no command has matched yet
+func (r *ScriptedRunner) Pending() []Step {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var pending []Step
+	for i, step := range r.Steps {
+		if i >= len(r.used) || !r.used[i] {
+			pending = append(pending, step)
+		}
+	}
+	return pending
+}
+
+func (r *ScriptedRunner) next(ctx context.Context, bin, dir string, args []string) (Step, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	call := Call{Bin: bin, Dir: dir, Args: append([]string{}, args...)}
+	r.calls = append(r.calls, call)
+
+	if err := ctx.Err(); err != nil {
+		return Step{}, TimeoutError{Command: call.CommandLine(), Cause: err}
+	}
+
+	if len(r.used) < len(r.Steps) {
+		r.used = append(r.used, make([]bool, len(r.Steps)-len(r.used))...)
+	}
+
+	commandLine := RedactArgs(append([]string{bin}, args...))
+	for i, step := range r.Steps {
+		if r.used[i] {
+			continue
+		}
+
+		if r.Ordered && matchesExactly(commandLine, step.Match) || !r.Ordered && matches(commandLine, step.Match) {
+			r.used[i] = true
+			return step, nil
+		}
+
+		if r.Ordered {
+			return Step{}, fmt.Errorf("unexpected command `%s`, expected `%s`", call.CommandLine(), strings.Join(step.Match, " "))
+		}
+	}
+
+	return Step{}, fmt.Errorf("unexpected command `%s`", call.CommandLine())
+}
+
+func (r *ScriptedRunner) result(bin string, args []string, step Step) error {
+	if r.Err != nil {
+		_, _ = io.WriteString(r.Err, step.Stderr)
+	}
+
+	if step.ExitCode == 0 {
+		return nil
+	}
+
+	tail := &tailWriter{}
+	_, _ = io.WriteString(tail, step.Stdout)
+	_, _ = io.WriteString(tail, step.Stderr)
+	return CommandError{
+		Command: redactCommand(append([]string{bin}, args...)),
+		Output:  tail.lines(),
+		Err:     ExitError{Code: step.ExitCode},
+	}
+}
+
+// matches reports whether the command line contains the expected arguments in order
+func matches(commandLine, expected []string) bool {
+	i := 0
+	for _, arg := range commandLine {
+		if i < len(expected) && arg == expected[i] {
+			i++
+		}
+	}
+	return i == len(expected)
+}
+
+// matchesExactly reports whether the command line without its absolute paths, which differ between machines, is the
+// expected one
+func matchesExactly(commandLine, expected []string) bool {
+	args := withoutAbsolutePaths(commandLine)
+	if len(args) != len(expected) {
+		return false
+	}
+
+	for i, arg := range args {
+		if arg != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutAbsolutePaths returns the arguments that are not absolute paths
+func withoutAbsolutePaths(args []string) []string {
+	var relative []string
+	for _, arg := range args {
+		if !filepath.IsAbs(arg) {
+			relative = append(relative, arg)
+		}
+	}
+	return relative
+}
+
+// Session is a sequence of commands recorded from a real runner
+type Session struct {
+	Steps []Step `json:"steps"`
+}
+
+// LoadSession reads a session written by WriteSession
+func LoadSession(path string) (Session, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Session{}, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(contents, &session); err != nil {
+		return Session{}, fmt.Errorf("unable to parse session %s: %w", path, err)
+	}
+	return session, nil
+}
+
+// WriteSession writes a session as JSON
+func WriteSession(path string, session Session) error {
+	contents, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// RecordingRunner runs commands with a ComposerRunner and records each of them with its output and exit code as a
+// step of Session. Absolute paths, such as the location of composer.phar, differ between machines and are left out of
+// the recorded matches, and credentials are redacted, as sessions end up in test fixtures.
+type RecordingRunner struct {
+	Runner  ComposerRunner
+	Session Session
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
+	stdout, stderr := &strings.Builder{}, &strings.Builder{}
+
+	runner := r.Runner
+	runner.Out, runner.Err = teeWriter(r.Runner.Out, stdout), teeWriter(r.Runner.Err, stderr)
+
+	err := runner.Run(ctx, bin, dir, args...)
+	return r.record(bin, args, stdout.String(), stderr.String(), err)
+}
+
+func (r *RecordingRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
+	stderr := &strings.Builder{}
+
+	runner := r.Runner
+	runner.Err = teeWriter(r.Runner.Err, stderr)
+
+	output, err := runner.RunWithOutput(ctx, bin, dir, args...)
+	return output, r.record(bin, args, output, stderr.String(), err)
+}
+
+func (r *RecordingRunner) record(bin string, args []string, stdout, stderr string, err error) error {
+	exitCode, exited := ExitCode(err)
+	if err != nil && !exited {
+		// the command did not run or was stopped, which cannot be replayed
+		return err
+	}
+
+	match := withoutAbsolutePaths(RedactArgs(append([]string{bin}, args...)))
+	r.Session.Steps = append(r.Session.Steps, Step{Match: match, Stdout: stdout, Stderr: stderr, ExitCode: exitCode})
+	return err
+}
+
+func teeWriter(w io.Writer, buf io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
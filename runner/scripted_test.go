@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry/libcfbuildpack/test"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitScripted(t *testing.T) {
+	spec.Run(t, "ScriptedRunner", testScripted, spec.Report(report.Terminal{}))
+}
+
+func testScripted(t *testing.T, when spec.G, it spec.S) {
+	var (
+		f   *test.BuildFactory
+		ctx context.Context
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		f = test.NewBuildFactory(t)
+		ctx = context.Background()
+	})
+
+	when("steps are scripted", func() {
+		var (
+			stdout *bytes.Buffer
+			runner *ScriptedRunner
+		)
+
+		it.Before(func() {
+			stdout = &bytes.Buffer{}
+			runner = &ScriptedRunner{
+				Steps: []Step{
+					{Match: []string{"php", "install"}, Stdout: "Installing dependencies from lock file\n"},
+					{Match: []string{"php", "check-platform-reqs"}, Stdout: "ext-gd n/a missing\n", ExitCode: 2},
+					{Match: []string{"php", "dump-autoload"}},
+				},
+				Out: stdout,
+			}
+		})
+
+		it("answers each command with the first matching step", func() {
+			output, err := runner.RunWithOutput(ctx, "php", "/app", "/tmp/composer.phar", "check-platform-reqs", "--no-dev")
+			Expect(output).To(Equal("ext-gd n/a missing\n"))
+			code, ok := ExitCode(err)
+			Expect(ok).To(BeTrue())
+			Expect(code).To(Equal(2))
+
+			Expect(runner.Run(ctx, "php", "/app", "/tmp/composer.phar", "install", "--no-progress")).To(Succeed())
+			Expect(stdout.String()).To(Equal("Installing dependencies from lock file\n"))
+
+			Expect(runner.Calls()).To(Equal([]Call{
+				{Bin: "php", Dir: "/app", Args: []string{"/tmp/composer.phar", "check-platform-reqs", "--no-dev"}},
+				{Bin: "php", Dir: "/app", Args: []string{"/tmp/composer.phar", "install", "--no-progress"}},
+			}))
+			Expect(runner.Pending()).To(Equal([]Step{{Match: []string{"php", "dump-autoload"}}}))
+		})
+
+		it("reports the output of failed commands", func() {
+			_, err := runner.RunWithOutput(ctx, "php", "/app", "check-platform-reqs")
+
+			var commandErr CommandError
+			Expect(errors.As(err, &commandErr)).To(BeTrue())
+			Expect(commandErr.Command).To(Equal("php check-platform-reqs"))
+			Expect(commandErr.Output).To(Equal([]string{"ext-gd n/a missing"}))
+		})
+
+		it("fails unexpected commands", func() {
+			Expect(runner.Run(ctx, "php", "/app", "update")).To(MatchError("unexpected command `php update`"))
+
+			Expect(runner.Run(ctx, "php", "/app", "install")).To(Succeed())
+			Expect(runner.Run(ctx, "php", "/app", "install")).To(MatchError("unexpected command `php install`"))
+		})
+
+		it("requires the order of the steps when ordered", func() {
+			runner.Ordered = true
+			Expect(runner.Run(ctx, "php", "/app", "dump-autoload")).To(MatchError("unexpected command `php dump-autoload`, expected `php install`"))
+		})
+
+		it("requires exactly the arguments of the step when ordered, apart from absolute paths", func() {
+			runner.Ordered = true
+			Expect(runner.Run(ctx, "php", "/app", "install", "--no-dev")).To(MatchError("unexpected command `php install --no-dev`, expected `php install`"))
+			Expect(runner.Run(ctx, "php", "/app", "/tmp/composer.phar", "install")).To(Succeed())
+		})
+
+		it("stops when the context has expired", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			Expect(errors.Is(runner.Run(cancelled, "php", "/app", "install"), context.Canceled)).To(BeTrue())
+		})
+
+		it("redacts the credentials of failed and stopped commands", func() {
+			runner.Steps = []Step{{Match: []string{"php", "config", "-g", "github-oauth.github.com"}, ExitCode: 1}}
+			err := runner.Run(ctx, "php", "/app", "config", "-g", "github-oauth.github.com", "s3cret-token")
+			Expect(err).To(MatchError("`php config -g github-oauth.github.com redacted` failed: exit status 1"))
+
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			err = runner.Run(cancelled, "php", "/app", "config", "-g", "github-oauth.github.com", "s3cret-token")
+			Expect(err).To(MatchError(HavePrefix("`php config -g github-oauth.github.com redacted` was cancelled")))
+		})
+	})
+
+	when("a session is recorded", func() {
+		it("replays the commands of a real runner", func() {
+			recorder := &RecordingRunner{Runner: ComposerRunner{Logger: f.Build.Logger}}
+
+			output, err := recorder.RunWithOutput(ctx, "sh", "", "-c", "echo checked")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal("checked\n"))
+			Expect(recorder.Run(ctx, "sh", "", "-c", "echo installing; echo failed >&2; exit 3")).To(HaveOccurred())
+
+			path := filepath.Join(test.ScratchDir(t, "session"), "session.json")
+			Expect(WriteSession(path, recorder.Session)).To(Succeed())
+
+			session, err := LoadSession(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.Steps).To(Equal([]Step{
+				{Match: []string{"sh", "-c", "echo checked"}, Stdout: "checked\n"},
+				{Match: []string{"sh", "-c", "echo installing; echo failed >&2; exit 3"}, Stdout: "installing\n", Stderr: "failed\n", ExitCode: 3},
+			}))
+
+			replay := NewReplayRunner(session)
+			output, err = replay.RunWithOutput(ctx, "sh", "/elsewhere", "-c", "echo checked")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal("checked\n"))
+
+			err = replay.Run(ctx, "sh", "/elsewhere", "-c", "echo installing; echo failed >&2; exit 3")
+			Expect(err).To(MatchError("`sh -c echo installing; echo failed >&2; exit 3` failed: exit status 3"))
+			Expect(replay.Pending()).To(BeEmpty())
+		})
+
+		it("redacts credentials in the written session and replays them", func() {
+			recorder := &RecordingRunner{Runner: ComposerRunner{Logger: f.Build.Logger}}
+			Expect(recorder.Run(ctx, "true", "", "config", "-g", "github-oauth.github.com", "s3cret-token")).To(Succeed())
+
+			path := filepath.Join(test.ScratchDir(t, "session"), "session.json")
+			Expect(WriteSession(path, recorder.Session)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).NotTo(ContainSubstring("s3cret-token"))
+
+			session, err := LoadSession(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.Steps[0].Match).To(Equal([]string{"true", "config", "-g", "github-oauth.github.com", "redacted"}))
+
+			replay := NewReplayRunner(session)
+			Expect(replay.Run(ctx, "true", "", "config", "-g", "github-oauth.github.com", "other-token")).To(Succeed())
+		})
+
+		it("leaves absolute paths out of the matches", func() {
+			recorder := &RecordingRunner{Runner: ComposerRunner{Logger: f.Build.Logger}}
+			Expect(recorder.Run(ctx, "true", "", "/tmp/composer.phar", "install")).To(Succeed())
+			Expect(recorder.Session.Steps[0].Match).To(Equal([]string{"true", "install"}))
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+)
+
+// StructuredRunner parses the output of Composer into events and renders them through the logger, followed by a
+// summary of the packages Composer installed, updated and removed
+type StructuredRunner struct {
+	Logger logger.Logger
+}
+
+func (r StructuredRunner) Run(ctx context.Context, bin, dir string, args ...string) error {
+	r.Logger.Debug("Running `%s` from directory '%s'", strings.Join(append([]string{bin}, args...), " "), dir)
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+
+	w := &eventWriter{renderer: &renderer{logger: r.Logger}}
+	tail := &tailWriter{}
+	output := observed(ctx, w, tail)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err := execute(ctx, cmd, tail)
+	w.flush()
+	w.renderer.summary(time.Since(start))
+
+	return err
+}
+
+// RunWithOutput returns the output of commands such as check-platform-reqs to the caller, which makes sense of it
+func (r StructuredRunner) RunWithOutput(ctx context.Context, bin, dir string, args ...string) (string, error) {
+	return ComposerRunner{Logger: r.Logger}.RunWithOutput(ctx, bin, dir, args...)
+}
+
+// eventWriter splits output into lines, including the carriage return separated lines of progress bars, and renders
+// each line as an event. Stdout and stderr share the writer, so exec calls Write from one goroutine at a time.
+type eventWriter struct {
+	buf      bytes.Buffer
+	renderer *renderer
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		i := bytes.IndexAny(w.buf.Bytes(), "\r\n")
+		if i < 0 {
+			break
+		}
+
+		line := string(w.buf.Next(i + 1))
+		w.renderer.render(ParseEvent(line[:len(line)-1]))
+	}
+
+	return len(p), nil
+}
+
+func (w *eventWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.renderer.render(ParseEvent(w.buf.String()))
+		w.buf.Reset()
+	}
+}
+
+// renderer logs events with consistent indentation and collects the package operations for the summary
+type renderer struct {
+	logger   logger.Logger
+	packages []Event
+	seen     map[string]bool
+	removed  int
+}
+
+func (r *renderer) render(event Event) {
+	// progress updates repeat the operation of a package
+	if event.Type == EventInstall || event.Type == EventUpdate {
+		if r.seen[event.Package] {
+			return
+		}
+		if r.seen == nil {
+			r.seen = map[string]bool{}
+		}
+		r.seen[event.Package] = true
+	}
+
+	switch event.Type {
+	case EventInstall:
+		r.packages = append(r.packages, event)
+		r.logger.Body("Installing %s (%s)", event.Package, event.Version)
+	case EventUpdate:
+		r.packages = append(r.packages, event)
+		r.logger.Body("Updating %s (%s => %s)", event.Package, event.FromVersion, event.Version)
+	case EventRemove:
+		r.removed++
+		r.logger.Body("Removing %s (%s)", event.Package, event.Version)
+	case EventDownload:
+		r.logger.Debug("Downloading %s (%s)", event.Package, event.Version)
+	case EventScript:
+		if event.ScriptEvent != "" {
+			r.logger.Body("Running %s script: %s", event.ScriptEvent, event.Message)
+		} else {
+			r.logger.Body("Running script: %s", event.Message)
+		}
+	case EventWarning:
+		r.logger.BodyWarning("%s", event.Message)
+	default:
+		if event.Message != "" {
+			r.logger.Body("%s", event.Message)
+		}
+	}
+}
+
+// summary logs a table of the installed and updated packages and the time Composer took
+func (r *renderer) summary(elapsed time.Duration) {
+	if len(r.packages) == 0 && r.removed == 0 {
+		return
+	}
+
+	installed, updated := 0, 0
+	nameWidth, versionWidth := len("Package"), len("Version")
+	for _, p := range r.packages {
+		if p.Type == EventInstall {
+			installed++
+		} else {
+			updated++
+		}
+
+		if len(p.Package) > nameWidth {
+			nameWidth = len(p.Package)
+		}
+		if len(p.Version) > versionWidth {
+			versionWidth = len(p.Version)
+		}
+	}
+
+	if len(r.packages) > 0 {
+		r.logger.Body("%-*s  %-*s  %s", nameWidth, "Package", versionWidth, "Version", "Operation")
+		for _, p := range r.packages {
+			r.logger.Body("%-*s  %-*s  %s", nameWidth, p.Package, versionWidth, p.Version, p.Type)
+		}
+	}
+
+	r.logger.Body("%d installed, %d updated, %d removed in %s", installed, updated, r.removed, elapsed.Round(time.Millisecond))
+}
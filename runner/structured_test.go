@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	bplogger "github.com/buildpack/libbuildpack/logger"
+	"github.com/cloudfoundry/libcfbuildpack/logger"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitStructuredRunner(t *testing.T) {
+	spec.Run(t, "StructuredRunner", testStructuredRunner, spec.Report(report.Terminal{}))
+}
+
+func testStructuredRunner(t *testing.T, when spec.G, it spec.S) {
+	var (
+		info   *bytes.Buffer
+		runner StructuredRunner
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		info = &bytes.Buffer{}
+		runner = StructuredRunner{Logger: logger.Logger{Logger: bplogger.NewLogger(nil, info)}}
+	})
+
+	it("renders the events and a summary of the packages", func() {
+		output := `Installing dependencies from lock file
+Package operations: 2 installs, 1 update, 1 removal
+  - Removing symfony/polyfill-php72 (v1.17.0)
+  - Installing psr/log (1.1.2): Downloading (0%)\r  - Installing psr/log (1.1.2): Extracting archive
+  - Upgrading monolog/monolog (1.25.1 => 1.25.3): Extracting archive
+  - Installing doctrine/instantiator (1.3.0): Extracting archive
+Warning: 100% done
+> @php artisan optimize`
+
+		err := runner.Run(context.Background(), "sh", "", "-c", `printf "$0" >&2`, strings.ReplaceAll(output, "%", "%%"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(info.String()).To(ContainSubstring("    Installing dependencies from lock file\n"))
+		Expect(info.String()).To(ContainSubstring("    Removing symfony/polyfill-php72 (v1.17.0)\n"))
+		Expect(info.String()).To(ContainSubstring("    Updating monolog/monolog (1.25.1 => 1.25.3)\n"))
+		Expect(info.String()).To(ContainSubstring("    100% done\n"))
+		Expect(info.String()).To(ContainSubstring("    Running script: @php artisan optimize\n"))
+		Expect(info.String()).To(ContainSubstring(`    Package                Version  Operation
+    psr/log                1.1.2    install
+    monolog/monolog        1.25.3   update
+    doctrine/instantiator  1.3.0    install
+    2 installed, 1 updated, 1 removed in `))
+	})
+
+	it("returns the error of the command", func() {
+		Expect(runner.Run(context.Background(), "sh", "", "-c", "echo failed; exit 3")).To(MatchError("`sh -c echo failed; exit 3` failed: exit status 3"))
+		Expect(info.String()).To(ContainSubstring("    failed\n"))
+		Expect(info.String()).NotTo(ContainSubstring("installed"))
+	})
+}
@@ -0,0 +1,114 @@
+package sbom
+
+import (
+	"encoding/json"
+)
+
+type cycloneDXDocument struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Tools []cycloneDXTool `json:"tools"`
+}
+
+type cycloneDXTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type cycloneDXComponent struct {
+	Type               string                 `json:"type"`
+	BOMRef             string                 `json:"bom-ref"`
+	Group              string                 `json:"group,omitempty"`
+	Name               string                 `json:"name"`
+	Version            string                 `json:"version,omitempty"`
+	Licenses           []cycloneDXLicense     `json:"licenses,omitempty"`
+	PURL               string                 `json:"purl"`
+	Hashes             []cycloneDXHash        `json:"hashes,omitempty"`
+	ExternalReferences []cycloneDXExternalRef `json:"externalReferences,omitempty"`
+}
+
+type cycloneDXLicense struct {
+	License    *cycloneDXLicenseChoice `json:"license,omitempty"`
+	Expression string                  `json:"expression,omitempty"`
+}
+
+type cycloneDXLicenseChoice struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// CycloneDX renders the components as a CycloneDX 1.4 JSON document. The document has no timestamp or serial
+// number, so it is identical across builds of the same packages.
+func CycloneDX(components []Component, toolVersion string) ([]byte, error) {
+	document := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Tools: []cycloneDXTool{{Vendor: "paketo-buildpacks", Name: "php-composer", Version: toolVersion}},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	for _, component := range components {
+		group, name := component.split()
+		c := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  component.PURL(),
+			Group:   group,
+			Name:    name,
+			Version: component.Version,
+			PURL:    component.PURL(),
+		}
+
+		for _, license := range component.Licenses {
+			c.Licenses = append(c.Licenses, cycloneDXLicenseFor(license))
+		}
+
+		// Composer records the SHA-1 of dist archives, when the repository provides one
+		if component.Shasum != "" {
+			c.Hashes = append(c.Hashes, cycloneDXHash{Algorithm: "SHA-1", Content: component.Shasum})
+		}
+
+		if component.DistURL != "" {
+			c.ExternalReferences = append(c.ExternalReferences, cycloneDXExternalRef{Type: "distribution", URL: component.DistURL})
+		}
+		if component.SourceURL != "" {
+			c.ExternalReferences = append(c.ExternalReferences, cycloneDXExternalRef{Type: "vcs", URL: component.SourceURL})
+		}
+
+		document.Components = append(document.Components, c)
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// cycloneDXLicenseFor records license expressions as SPDX expressions, known SPDX identifiers by id and any other
+// license by name
+func cycloneDXLicenseFor(license string) cycloneDXLicense {
+	if isLicenseExpression(license) {
+		return cycloneDXLicense{Expression: spdxLicenseExpression(license, map[string]string{})}
+	}
+
+	if id, ok := spdxLicenseID(license); ok {
+		return cycloneDXLicense{License: &cycloneDXLicenseChoice{ID: id}}
+	}
+	return cycloneDXLicense{License: &cycloneDXLicenseChoice{Name: license}}
+}
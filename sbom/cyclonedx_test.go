@@ -0,0 +1,63 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitCycloneDX(t *testing.T) {
+	spec.Run(t, "CycloneDX", testCycloneDX, spec.Report(report.Terminal{}))
+}
+
+func testCycloneDX(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("renders a CycloneDX 1.4 document", func() {
+		buf, err := CycloneDX([]Component{{
+			Name:      "monolog/monolog",
+			Version:   "1.25.1",
+			Licenses:  []string{"MIT", "(GPL-2.0-only or proprietary)", "Commercial"},
+			SourceURL: "https://github.com/Seldaek/monolog.git",
+			DistURL:   "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5",
+			Shasum:    "da39a3ee",
+		}}, "1.2.3")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(buf).To(MatchJSON(`{
+	"bomFormat": "CycloneDX",
+	"specVersion": "1.4",
+	"version": 1,
+	"metadata": {"tools": [{"vendor": "paketo-buildpacks", "name": "php-composer", "version": "1.2.3"}]},
+	"components": [{
+		"type": "library",
+		"bom-ref": "pkg:composer/monolog/monolog@1.25.1",
+		"group": "monolog",
+		"name": "monolog",
+		"version": "1.25.1",
+		"licenses": [{"license": {"id": "MIT"}}, {"expression": "(GPL-2.0-only OR LicenseRef-proprietary)"}, {"license": {"name": "Commercial"}}],
+		"purl": "pkg:composer/monolog/monolog@1.25.1",
+		"hashes": [{"alg": "SHA-1", "content": "da39a3ee"}],
+		"externalReferences": [
+			{"type": "distribution", "url": "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5"},
+			{"type": "vcs", "url": "https://github.com/Seldaek/monolog.git"}
+		]
+	}]
+}`))
+	})
+
+	it("renders an empty component list", func() {
+		buf, err := CycloneDX(nil, "1.2.3")
+		Expect(err).NotTo(HaveOccurred())
+
+		document := map[string]interface{}{}
+		Expect(json.Unmarshal(buf, &document)).To(Succeed())
+		Expect(document["components"]).To(BeEmpty())
+		Expect(document["components"]).NotTo(BeNil())
+	})
+}
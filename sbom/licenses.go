@@ -0,0 +1,91 @@
+package sbom
+
+import (
+	"regexp"
+	"strings"
+)
+
+// spdxLicenseIDs are the SPDX license identifiers that PHP packages commonly declare, keyed by their lower case form.
+// Composer asks for SPDX identifiers, but does not enforce them.
+var spdxLicenseIDs = map[string]string{}
+
+func init() {
+	for _, id := range []string{
+		"0BSD", "AFL-3.0", "AGPL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.0", "Apache-1.1", "Apache-2.0",
+		"Artistic-1.0", "Artistic-2.0", "BSD-1-Clause", "BSD-2-Clause", "BSD-3-Clause", "BSD-4-Clause", "BSL-1.0",
+		"CC-BY-3.0", "CC-BY-4.0", "CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "CDDL-1.1", "CECILL-2.1",
+		"EPL-1.0", "EPL-2.0", "EUPL-1.1", "EUPL-1.2", "GPL-1.0", "GPL-1.0+", "GPL-1.0-only", "GPL-1.0-or-later",
+		"GPL-2.0", "GPL-2.0+", "GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0", "GPL-3.0+", "GPL-3.0-only",
+		"GPL-3.0-or-later", "ISC", "LGPL-2.0", "LGPL-2.0+", "LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1",
+		"LGPL-2.1+", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0", "LGPL-3.0+", "LGPL-3.0-only",
+		"LGPL-3.0-or-later", "MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "NCSA", "OpenSSL", "OSL-3.0", "PHP-3.0",
+		"PHP-3.01", "PostgreSQL", "Python-2.0", "Ruby", "Unlicense", "UPL-1.0", "W3C", "WTFPL", "X11", "Zend-2.0",
+		"Zlib", "ZPL-2.1",
+	} {
+		spdxLicenseIDs[strings.ToLower(id)] = id
+	}
+}
+
+var (
+	licenseToken  = regexp.MustCompile(`[()]|[^\s()]+`)
+	licenseRefBad = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+)
+
+// spdxLicenseID returns the canonical form of a known SPDX license identifier
+func spdxLicenseID(license string) (string, bool) {
+	id, ok := spdxLicenseIDs[strings.ToLower(license)]
+	return id, ok
+}
+
+// isLicenseExpression reports whether a license combines several licenses, e.g. "(MIT or GPL-2.0-only)"
+func isLicenseExpression(license string) bool {
+	return strings.ContainsAny(license, " ()")
+}
+
+// spdxLicenseExpression turns a license or license expression from composer.json into a valid SPDX expression.
+// Names that are not SPDX identifiers, such as "proprietary", become LicenseRef-<name>, which refs maps back to the
+// declared name.
+func spdxLicenseExpression(license string, refs map[string]string) string {
+	var tokens, words []string
+
+	// a name of several words between operators is a single license
+	flush := func() {
+		if len(words) == 0 {
+			return
+		}
+		name := strings.Join(words, " ")
+		words = nil
+
+		switch {
+		case len(tokens) > 0 && tokens[len(tokens)-1] == "WITH":
+			// license exceptions such as Classpath-exception-2.0 follow WITH
+			tokens = append(tokens, name)
+		default:
+			if id, ok := spdxLicenseID(name); ok {
+				tokens = append(tokens, id)
+				return
+			}
+			ref := "LicenseRef-" + strings.Trim(licenseRefBad.ReplaceAllString(name, "-"), "-")
+			refs[ref] = name
+			tokens = append(tokens, ref)
+		}
+	}
+
+	for _, token := range licenseToken.FindAllString(license, -1) {
+		switch upper := strings.ToUpper(token); {
+		case token == "(" || token == ")":
+			flush()
+			tokens = append(tokens, token)
+		case upper == "AND" || upper == "OR" || upper == "WITH":
+			flush()
+			tokens = append(tokens, upper)
+		default:
+			words = append(words, token)
+		}
+	}
+	flush()
+
+	expression := strings.Join(tokens, " ")
+	expression = strings.ReplaceAll(expression, "( ", "(")
+	return strings.ReplaceAll(expression, " )", ")")
+}
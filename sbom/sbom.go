@@ -0,0 +1,102 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/php-composer/manifest"
+)
+
+// Component is an installed Composer package as recorded in a Software Bill of Materials
+type Component struct {
+	Name      string
+	Version   string
+	Licenses  []string
+	SourceURL string
+	DistURL   string
+	Reference string
+	Shasum    string
+}
+
+// FromPackages converts packages from composer.lock or installed.json into components, sorted by name
+func FromPackages(packages []manifest.Package) []Component {
+	var components []Component
+	for _, pkg := range packages {
+		component := Component{
+			Name:     pkg.Name,
+			Version:  pkg.Version,
+			Licenses: append([]string{}, pkg.License...),
+		}
+
+		if pkg.Source != nil {
+			component.SourceURL = pkg.Source.URL
+			component.Reference = pkg.Source.Reference
+		}
+		if pkg.Dist != nil {
+			component.DistURL = pkg.Dist.URL
+			component.Shasum = pkg.Dist.Shasum
+			if pkg.Dist.Reference != "" {
+				component.Reference = pkg.Dist.Reference
+			}
+		}
+
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components
+}
+
+// PURL returns the package URL of the component, e.g. pkg:composer/monolog/monolog@1.25.1
+func (c Component) PURL() string {
+	purl := "pkg:composer/" + c.Name
+	if vendor, name := c.split(); vendor != "" {
+		purl = fmt.Sprintf("pkg:composer/%s/%s", url.PathEscape(vendor), url.PathEscape(name))
+	}
+
+	if c.Version != "" {
+		purl += "@" + url.PathEscape(c.Version)
+	}
+	return purl
+}
+
+// split returns the vendor and project parts of the package name
+func (c Component) split() (string, string) {
+	if i := strings.Index(c.Name, "/"); i >= 0 {
+		return c.Name[:i], c.Name[i+1:]
+	}
+	return "", c.Name
+}
+
+// LicenseSummary counts the components per license, e.g. "MIT (12), BSD-3-Clause (2)", most common first.
+// Components without a license are counted as "unknown".
+func LicenseSummary(components []Component) string {
+	counts := map[string]int{}
+	for _, component := range components {
+		if len(component.Licenses) == 0 {
+			counts["unknown"]++
+		}
+		for _, license := range component.Licenses {
+			counts[license]++
+		}
+	}
+
+	var licenses []string
+	for license := range counts {
+		licenses = append(licenses, license)
+	}
+	sort.Slice(licenses, func(i, j int) bool {
+		if counts[licenses[i]] != counts[licenses[j]] {
+			return counts[licenses[i]] > counts[licenses[j]]
+		}
+		return licenses[i] < licenses[j]
+	})
+
+	var summary []string
+	for _, license := range licenses {
+		summary = append(summary, fmt.Sprintf("%s (%d)", license, counts[license]))
+	}
+	return strings.Join(summary, ", ")
+}
@@ -0,0 +1,56 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/php-composer/manifest"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitSBOM(t *testing.T) {
+	spec.Run(t, "SBOM", testSBOM, spec.Report(report.Terminal{}))
+}
+
+func testSBOM(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("converts packages into sorted components", func() {
+		components := FromPackages([]manifest.Package{
+			{
+				Name:    "psr/log",
+				Version: "1.1.2",
+				License: manifest.StringList{"MIT"},
+				Source:  &manifest.Reference{URL: "https://github.com/php-fig/log.git", Reference: "446d54b"},
+			},
+			{
+				Name:    "monolog/monolog",
+				Version: "1.25.1",
+				Dist:    &manifest.Reference{URL: "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5", Reference: "70e65a5", Shasum: "da39a3ee"},
+			},
+		})
+
+		Expect(components).To(Equal([]Component{
+			{Name: "monolog/monolog", Version: "1.25.1", Licenses: []string{}, DistURL: "https://api.github.com/repos/Seldaek/monolog/zipball/70e65a5", Reference: "70e65a5", Shasum: "da39a3ee"},
+			{Name: "psr/log", Version: "1.1.2", Licenses: []string{"MIT"}, SourceURL: "https://github.com/php-fig/log.git", Reference: "446d54b"},
+		}))
+	})
+
+	it("builds package URLs", func() {
+		Expect(Component{Name: "monolog/monolog", Version: "1.25.1"}.PURL()).To(Equal("pkg:composer/monolog/monolog@1.25.1"))
+		Expect(Component{Name: "acme/app", Version: "dev-main#1234"}.PURL()).To(Equal("pkg:composer/acme/app@dev-main%231234"))
+	})
+
+	it("summarizes licenses", func() {
+		Expect(LicenseSummary([]Component{
+			{Licenses: []string{"MIT"}},
+			{Licenses: []string{"BSD-3-Clause"}},
+			{Licenses: []string{"MIT"}},
+			{},
+		})).To(Equal("MIT (2), BSD-3-Clause (1), unknown (1)"))
+	})
+}
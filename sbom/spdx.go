@@ -0,0 +1,165 @@
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const noAssertion = "NOASSERTION"
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+// spdxExtractedLicense describes a LicenseRef, which a document has to do for every LicenseRef it uses
+type spdxExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	Name          string `json:"name"`
+	ExtractedText string `json:"extractedText"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	Element      string `json:"spdxElementId"`
+	Type         string `json:"relationshipType"`
+	RelatedToRef string `json:"relatedSpdxElement"`
+}
+
+var spdxIDUnsafe = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// SPDX renders the components as an SPDX 2.2 JSON document. The namespace is derived from the components, so only
+// the creation time differs between builds of the same packages.
+func SPDX(name string, components []Component, toolVersion string, created time.Time) ([]byte, error) {
+	document := spdxDocument{
+		SPDXVersion: "SPDX-2.2",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        name,
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: paketo-buildpacks/php-composer-%s", toolVersion)},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	refs := map[string]string{}
+	digest := sha256.New()
+	for _, component := range components {
+		fmt.Fprintln(digest, component.PURL(), component.Reference)
+
+		pkg := spdxPackage{
+			SPDXID:           "SPDXRef-Package-" + strings.Trim(spdxIDUnsafe.ReplaceAllString(component.Name+"-"+component.Version, "-"), "-"),
+			Name:             component.Name,
+			VersionInfo:      component.Version,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  spdxLicense(component.Licenses, refs),
+			CopyrightText:    noAssertion,
+			ExternalRefs: []spdxExternalRef{
+				{Category: "PACKAGE-MANAGER", Type: "purl", Locator: component.PURL()},
+			},
+		}
+
+		if component.DistURL != "" {
+			pkg.DownloadLocation = component.DistURL
+		} else if component.SourceURL != "" {
+			pkg.DownloadLocation = "git+" + component.SourceURL
+			if component.Reference != "" {
+				pkg.DownloadLocation += "@" + component.Reference
+			}
+		}
+
+		if component.Shasum != "" {
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: "SHA1", Value: component.Shasum})
+		}
+
+		document.Packages = append(document.Packages, pkg)
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			Element:      document.SPDXID,
+			Type:         "DESCRIBES",
+			RelatedToRef: pkg.SPDXID,
+		})
+	}
+
+	var ids []string
+	for id := range refs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		document.ExtractedLicenses = append(document.ExtractedLicenses, spdxExtractedLicense{
+			LicenseID:     id,
+			Name:          refs[id],
+			ExtractedText: fmt.Sprintf("The license %q declared in composer.json, which is not an SPDX license identifier", refs[id]),
+		})
+	}
+
+	document.DocumentNamespace = fmt.Sprintf("https://paketo.io/spdx/php-composer/%s-%s", name, hex.EncodeToString(digest.Sum(nil))[:16])
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// spdxLicense joins the licenses of a package into an SPDX expression. Composer lists alternative licenses, so they
+// are combined with OR.
+func spdxLicense(licenses []string, refs map[string]string) string {
+	var expressions []string
+	for _, license := range licenses {
+		if expression := spdxLicenseExpression(license, refs); expression != "" {
+			expressions = append(expressions, expression)
+		}
+	}
+
+	switch len(expressions) {
+	case 0:
+		return noAssertion
+	case 1:
+		return expressions[0]
+	default:
+		return "(" + strings.Join(expressions, " OR ") + ")"
+	}
+}
@@ -0,0 +1,81 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitSPDX(t *testing.T) {
+	spec.Run(t, "SPDX", testSPDX, spec.Report(report.Terminal{}))
+}
+
+func testSPDX(t *testing.T, when spec.G, it spec.S) {
+	var components []Component
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		components = []Component{
+			{Name: "monolog/monolog", Version: "1.25.1", Licenses: []string{"MIT"}, DistURL: "https://example.com/monolog.zip", Shasum: "da39a3ee"},
+			{Name: "psr/log", Version: "1.1.2", Licenses: []string{"MIT", "Apache-2.0"}, SourceURL: "https://github.com/php-fig/log.git", Reference: "446d54b"},
+		}
+	})
+
+	it("renders an SPDX 2.2 document", func() {
+		buf, err := SPDX("php-composer-packages", components, "1.2.3", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+
+		document := spdxDocument{}
+		Expect(json.Unmarshal(buf, &document)).To(Succeed())
+		Expect(document.SPDXVersion).To(Equal("SPDX-2.2"))
+		Expect(document.CreationInfo.Created).To(Equal("2020-01-02T03:04:05Z"))
+		Expect(document.CreationInfo.Creators).To(ConsistOf("Tool: paketo-buildpacks/php-composer-1.2.3"))
+		Expect(document.DocumentNamespace).To(HavePrefix("https://paketo.io/spdx/php-composer/php-composer-packages-"))
+
+		Expect(document.Packages).To(HaveLen(2))
+		Expect(document.Packages[0].SPDXID).To(Equal("SPDXRef-Package-monolog-monolog-1.25.1"))
+		Expect(document.Packages[0].DownloadLocation).To(Equal("https://example.com/monolog.zip"))
+		Expect(document.Packages[0].Checksums).To(ConsistOf(spdxChecksum{Algorithm: "SHA1", Value: "da39a3ee"}))
+		Expect(document.Packages[1].DownloadLocation).To(Equal("git+https://github.com/php-fig/log.git@446d54b"))
+		Expect(document.Packages[1].LicenseDeclared).To(Equal("(MIT OR Apache-2.0)"))
+		Expect(document.Packages[1].ExternalRefs).To(ConsistOf(spdxExternalRef{Category: "PACKAGE-MANAGER", Type: "purl", Locator: "pkg:composer/psr/log@1.1.2"}))
+		Expect(document.Relationships).To(ContainElement(spdxRelationship{Element: "SPDXRef-DOCUMENT", Type: "DESCRIBES", RelatedToRef: "SPDXRef-Package-psr-log-1.1.2"}))
+	})
+
+	it("declares names that are not SPDX identifiers as LicenseRefs", func() {
+		components = []Component{
+			{Name: "acme/internal", Version: "1.0.0", Licenses: []string{"proprietary"}},
+			{Name: "acme/dual", Version: "2.0.0", Licenses: []string{"(mit or Acme Commercial+)"}},
+		}
+
+		buf, err := SPDX("php-composer-packages", components, "1.2.3", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		document := spdxDocument{}
+		Expect(json.Unmarshal(buf, &document)).To(Succeed())
+		Expect(document.Packages[0].LicenseDeclared).To(Equal("LicenseRef-proprietary"))
+		Expect(document.Packages[1].LicenseDeclared).To(Equal("(MIT OR LicenseRef-Acme-Commercial)"))
+		Expect(document.ExtractedLicenses).To(HaveLen(2))
+		Expect(document.ExtractedLicenses[0].Name).To(Equal("Acme Commercial+"))
+		Expect(document.ExtractedLicenses[1].LicenseID).To(Equal("LicenseRef-proprietary"))
+		Expect(document.ExtractedLicenses[1].Name).To(Equal("proprietary"))
+	})
+
+	it("derives the namespace from the components", func() {
+		first, err := SPDX("php-composer-packages", components, "1.2.3", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		second, err := SPDX("php-composer-packages", components[:1], "1.2.3", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		namespace := func(buf []byte) string {
+			document := spdxDocument{}
+			Expect(json.Unmarshal(buf, &document)).To(Succeed())
+			return document.DocumentNamespace
+		}
+		Expect(namespace(first)).NotTo(Equal(namespace(second)))
+	})
+}